@@ -0,0 +1,149 @@
+package ai
+
+// GlobalMean predicts the mean of every training rating for everyone, it is the
+// floor every other predictor has to beat
+type GlobalMean struct {
+	Mean   float64
+	matrix *Matrix
+}
+
+func NewGlobalMean(users Users) *GlobalMean {
+	m := NewMatrix(users)
+	return &GlobalMean{Mean: m.GlobalMean(), matrix: m}
+}
+
+func (g *GlobalMean) Predict(userID, movieID int) (float64, bool) {
+	return g.Mean, true
+}
+
+// TopN gives every unrated movie the same score, so the list is in movie ID order
+func (g *GlobalMean) TopN(userID, n int) []Rating {
+	return rankUnrated(g.matrix, userID, n, func(int) float64 { return g.Mean })
+}
+
+// BiasOptions shrinks user and item biases towards zero, a bias is its summed
+// residuals divided by the rating count plus the regularisation
+type BiasOptions struct {
+	UserRegularization float64
+	ItemRegularization float64
+	Iterations         int
+}
+
+var DefaultBias = BiasOptions{UserRegularization: 10, ItemRegularization: 25, Iterations: 10}
+
+// Biases predicts the global mean plus how much higher than average the user rates
+// and the movie is rated
+type Biases struct {
+	Options  BiasOptions
+	Mean     float64
+	UserBias map[int]float64
+	ItemBias map[int]float64
+	matrix   *Matrix
+}
+
+// NewBiases fits item and user biases in turn, each on what the other leaves over
+func NewBiases(users Users, opts BiasOptions) *Biases {
+	m := NewMatrix(users)
+	b := &Biases{
+		Options:  opts,
+		Mean:     m.GlobalMean(),
+		UserBias: make(map[int]float64, len(m.UserIDs())),
+		ItemBias: make(map[int]float64, len(m.MovieIDs())),
+		matrix:   m,
+	}
+	for it := 0; it < opts.Iterations; it++ {
+		for _, movieID := range m.MovieIDs() {
+			col, _ := m.MovieColumn(movieID)
+			sum := 0.0
+			for i, userID := range col.Index {
+				sum += col.Value[i] - b.Mean - b.UserBias[userID]
+			}
+			b.ItemBias[movieID] = sum / (opts.ItemRegularization + float64(len(col.Index)))
+		}
+		for _, userID := range m.UserIDs() {
+			row, _ := m.UserRow(userID)
+			sum := 0.0
+			for i, movieID := range row.Index {
+				sum += row.Value[i] - b.Mean - b.ItemBias[movieID]
+			}
+			b.UserBias[userID] = sum / (opts.UserRegularization + float64(len(row.Index)))
+		}
+	}
+	return b
+}
+
+// Predict works for unknown users and movies too, their bias is zero
+func (b *Biases) Predict(userID, movieID int) (float64, bool) {
+	return b.Mean + b.UserBias[userID] + b.ItemBias[movieID], true
+}
+
+// TopN ranks by item bias, the user bias moves every score equally
+func (b *Biases) TopN(userID, n int) []Rating {
+	return rankUnrated(b.matrix, userID, n, func(movieID int) float64 {
+		score, _ := b.Predict(userID, movieID)
+		return score
+	})
+}
+
+// MostPopular recommends the movies with the most ratings, the scores are the counts
+type MostPopular struct {
+	matrix *Matrix
+}
+
+func NewMostPopular(users Users) *MostPopular {
+	return &MostPopular{matrix: NewMatrix(users)}
+}
+
+func (p *MostPopular) TopN(userID, n int) []Rating {
+	return rankUnrated(p.matrix, userID, n, func(movieID int) float64 {
+		col, _ := p.matrix.MovieColumn(movieID)
+		return float64(len(col.Index))
+	})
+}
+
+// TopRated recommends by Bayesian average, each movie's mean is pulled towards the
+// global mean as if it had Prior extra ratings at that mean, so a movie rated 5 by
+// a single user does not come first
+type TopRated struct {
+	Prior  float64
+	matrix *Matrix
+}
+
+// DefaultPrior counts the global mean as this many ratings
+const DefaultPrior = 20
+
+func NewTopRated(users Users, prior float64) *TopRated {
+	return &TopRated{Prior: prior, matrix: NewMatrix(users)}
+}
+
+func (t *TopRated) Predict(userID, movieID int) (float64, bool) {
+	return bayesianAverage(t.matrix, movieID, t.Prior), true
+}
+
+func (t *TopRated) TopN(userID, n int) []Rating {
+	return rankUnrated(t.matrix, userID, n, func(movieID int) float64 {
+		return bayesianAverage(t.matrix, movieID, t.Prior)
+	})
+}
+
+func bayesianAverage(m *Matrix, movieID int, prior float64) float64 {
+	col, _ := m.MovieColumn(movieID)
+	count := float64(len(col.Index))
+	if count+prior == 0 {
+		return m.GlobalMean()
+	}
+	return (prior*m.GlobalMean() + count*m.MovieMean(movieID)) / (prior + count)
+}
+
+// rankUnrated scores every movie in m the user has not rated and keeps the n best,
+// an unknown user has rated nothing
+func rankUnrated(m *Matrix, userID, n int, score func(movieID int) float64) []Rating {
+	row, _ := m.UserRow(userID)
+	recs := make([]Rating, 0, len(m.MovieIDs()))
+	for _, movieID := range m.MovieIDs() {
+		if _, ok := row.At(movieID); !ok {
+			recs = append(recs, Rating{MovieID: movieID, Score: score(movieID)})
+		}
+	}
+	return topRatings(recs, n)
+}
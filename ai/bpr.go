@@ -0,0 +1,173 @@
+package ai
+
+import (
+	"math"
+	"math/rand"
+)
+
+// Sampling picks how BPR draws the movie a user is assumed not to want
+type Sampling int
+
+const (
+	// UniformSampling draws any movie the user has not interacted with
+	UniformSampling Sampling = iota
+	// PopularSampling draws movies in proportion to how often they appear, popular
+	// movies the user skipped say more than obscure ones
+	PopularSampling
+	// DynamicSampling draws Candidates movies uniformly and keeps the one the model
+	// currently scores highest, the hardest negative
+	DynamicSampling
+)
+
+// BPRConfig holds the training parameters of a BPR model
+type BPRConfig struct {
+	Sampling       Sampling
+	Candidates     int // draws per negative for DynamicSampling
+	Factors        int
+	LearningRate   float64
+	Regularization float64
+	Epochs         int
+	Seed           int64
+}
+
+// DefaultBPR is a reasonable starting point for binarised MovieLens 100k
+var DefaultBPR = BPRConfig{
+	Sampling:       UniformSampling,
+	Candidates:     5,
+	Factors:        32,
+	LearningRate:   0.05,
+	Regularization: 0.002,
+	Epochs:         30,
+	Seed:           1,
+}
+
+// BPR is Bayesian Personalised Ranking, it learns from implicit feedback such as
+// views or votes that a user prefers the movies they interacted with over the rest.
+// Every rating in its training users counts as one interaction, whatever the score
+type BPR struct {
+	Config      BPRConfig
+	ItemBias    map[int]float64
+	UserFactors map[int][]float64
+	ItemFactors map[int][]float64
+	matrix      *Matrix
+}
+
+// Binarize keeps the ratings of at least threshold as implicit interactions with a
+// score of 1
+func Binarize(users Users, threshold float64) Users {
+	out := make(Users, 0, len(users))
+	for _, user := range users {
+		kept := User{ID: user.ID, Name: user.Name}
+		for _, r := range user.Ratings {
+			if r.Score >= threshold {
+				kept.Ratings = append(kept.Ratings, Rating{MovieID: r.MovieID, Score: 1, Timestamp: r.Timestamp})
+			}
+		}
+		if len(kept.Ratings) > 0 {
+			out = append(out, kept)
+		}
+	}
+	return out
+}
+
+// NewBPR trains on one positive and one sampled negative per step, as many steps
+// per epoch as there are interactions
+func NewBPR(users Users, config BPRConfig) *BPR {
+	b := &BPR{
+		Config:      config,
+		ItemBias:    make(map[int]float64),
+		UserFactors: make(map[int][]float64),
+		ItemFactors: make(map[int][]float64),
+		matrix:      NewMatrix(users),
+	}
+	data := b.matrix.entries()
+	movieIDs := b.matrix.MovieIDs()
+	if len(data) == 0 || len(movieIDs) < 2 {
+		return b
+	}
+
+	rng := rand.New(rand.NewSource(config.Seed))
+	for _, userID := range b.matrix.UserIDs() {
+		b.UserFactors[userID] = randomFactors(rng, config.Factors)
+	}
+	for _, movieID := range movieIDs {
+		b.ItemFactors[movieID] = randomFactors(rng, config.Factors)
+	}
+
+	lr, reg := config.LearningRate, config.Regularization
+	for epoch := 0; epoch < config.Epochs; epoch++ {
+		for step := 0; step < len(data); step++ {
+			e := data[rng.Intn(len(data))]
+			row, _ := b.matrix.UserRow(e.userID)
+			if len(row.Index) == len(movieIDs) {
+				continue
+			}
+			j := b.negative(rng, e.userID, row, data, movieIDs)
+
+			p, qi, qj := b.UserFactors[e.userID], b.ItemFactors[e.movieID], b.ItemFactors[j]
+			x := b.ItemBias[e.movieID] - b.ItemBias[j] + dot(p, qi) - dot(p, qj)
+			g := 1 / (1 + math.Exp(x)) // derivative of ln sigmoid(x)
+
+			b.ItemBias[e.movieID] += lr * (g - reg*b.ItemBias[e.movieID])
+			b.ItemBias[j] += lr * (-g - reg*b.ItemBias[j])
+			for k := range p {
+				pk := p[k]
+				p[k] += lr * (g*(qi[k]-qj[k]) - reg*pk)
+				qi[k] += lr * (g*pk - reg*qi[k])
+				qj[k] += lr * (-g*pk - reg*qj[k])
+			}
+		}
+	}
+	return b
+}
+
+// negative draws a movie the user has not interacted with using the configured sampling
+func (b *BPR) negative(rng *rand.Rand, userID int, row Vector, data []entry, movieIDs []int) int {
+	draw := func() int {
+		for {
+			var j int
+			if b.Config.Sampling == PopularSampling {
+				j = data[rng.Intn(len(data))].movieID
+			} else {
+				j = movieIDs[rng.Intn(len(movieIDs))]
+			}
+			if _, ok := row.At(j); !ok {
+				return j
+			}
+		}
+	}
+
+	j := draw()
+	if b.Config.Sampling != DynamicSampling {
+		return j
+	}
+	best, _ := b.Score(userID, j)
+	for c := 1; c < b.Config.Candidates; c++ {
+		other := draw()
+		if score, _ := b.Score(userID, other); score > best {
+			j, best = other, score
+		}
+	}
+	return j
+}
+
+// Score ranks movies for a user, it is not a rating and only the order means anything
+func (b *BPR) Score(userID, movieID int) (float64, bool) {
+	p, userOK := b.UserFactors[userID]
+	q, itemOK := b.ItemFactors[movieID]
+	if !userOK || !itemOK {
+		return 0.0, false
+	}
+	return b.ItemBias[movieID] + dot(p, q), true
+}
+
+// TopN scores every movie the user has not interacted with and returns the n best
+func (b *BPR) TopN(userID, n int) []Rating {
+	if _, ok := b.UserFactors[userID]; !ok {
+		return coldStart(b.matrix, userID, n)
+	}
+	return rankUnrated(b.matrix, userID, n, func(movieID int) float64 {
+		score, _ := b.Score(userID, movieID)
+		return score
+	})
+}
@@ -0,0 +1,470 @@
+package ai
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+)
+
+// ClusterMethod picks how users are grouped
+type ClusterMethod int
+
+const (
+	// KMeans moves each centre to the mean of its users
+	KMeans ClusterMethod = iota
+	// KMedoids keeps each centre on the user with the least total distance to the rest
+	// of its cluster, slower but less pulled about by outliers
+	KMedoids
+)
+
+// ClusterOptions sets the method, the number of clusters and when to stop
+type ClusterOptions struct {
+	Method     ClusterMethod
+	K          int
+	Iterations int // stop after this many rounds if assignments still change
+	Seed       int64
+}
+
+var DefaultClustering = ClusterOptions{Method: KMeans, K: 8, Iterations: 50, Seed: 1}
+
+// Clustering assigns every user to one of K clusters
+type Clustering struct {
+	Options     ClusterOptions
+	Assignments map[int]int
+	Members     [][]int     // user IDs in each cluster, ascending
+	Centroids   [][]float64 // the mean, or for KMedoids the medoid's vector
+	Medoids     []int       // the user at the centre of each cluster, KMedoids only
+	Names       []string    // set by Name
+	Genres      [][]string  // the genres each cluster is named after, set by Name
+}
+
+// Segment is a named cluster for reporting
+type Segment struct {
+	ID     int
+	Name   string
+	Size   int
+	Genres []string
+}
+
+// points holds vectors in ID order and their pairwise distances once needed
+type points struct {
+	ids  []int
+	x    [][]float64
+	dist [][]float64
+}
+
+func newPoints(vectors map[int][]float64) *points {
+	p := &points{ids: make([]int, 0, len(vectors))}
+	for id := range vectors {
+		p.ids = append(p.ids, id)
+	}
+	sort.Ints(p.ids)
+	for _, id := range p.ids {
+		p.x = append(p.x, vectors[id])
+	}
+	return p
+}
+
+// distances is the Euclidean distance between every pair of points
+func (p *points) distances() [][]float64 {
+	if p.dist != nil {
+		return p.dist
+	}
+	p.dist = make([][]float64, len(p.x))
+	for i := range p.x {
+		p.dist[i] = make([]float64, len(p.x))
+		for j := 0; j < i; j++ {
+			d := distance(p.x[i], p.x[j])
+			p.dist[i][j], p.dist[j][i] = d, d
+		}
+	}
+	return p.dist
+}
+
+func distance(a, b []float64) float64 {
+	sum := 0.0
+	for i := range a {
+		d := a[i] - b[i]
+		sum += d * d
+	}
+	return math.Sqrt(sum)
+}
+
+// RatingVectors is every user's ratings less their mean over all movies in users,
+// scaled to unit length so users who rate a lot are not far from everyone for that
+// alone. Unrated movies are zero, at the user's mean
+func RatingVectors(users Users) map[int][]float64 {
+	m := NewMatrix(users)
+	vectors := make(map[int][]float64, len(m.UserIDs()))
+	for _, userID := range m.UserIDs() {
+		row, _ := m.UserRow(userID)
+		mean := m.UserMean(userID)
+		v := make([]float64, len(m.MovieIDs()))
+		for i, movieID := range row.Index {
+			v[sort.SearchInts(m.MovieIDs(), movieID)] = row.Value[i] - mean
+		}
+		length := norm(v, 0)
+		for i := range v {
+			v[i] = ratio(v[i], length)
+		}
+		vectors[userID] = v
+	}
+	return vectors
+}
+
+// Cluster groups the vectors, keyed by user ID, into opts.K clusters. The vectors can
+// be RatingVectors or the UserFactors of a Factorization
+func Cluster(vectors map[int][]float64, opts ClusterOptions) *Clustering {
+	return cluster(newPoints(vectors), opts)
+}
+
+// ChooseK clusters once for every k in ks and returns the clustering with the best
+// silhouette along with the silhouette of each k
+func ChooseK(vectors map[int][]float64, ks []int, opts ClusterOptions) (*Clustering, []float64) {
+	p := newPoints(vectors)
+	scores := make([]float64, len(ks))
+	var best *Clustering
+	bestAt := 0
+	for i, k := range ks {
+		opts.K = k
+		c := cluster(p, opts)
+		scores[i] = silhouette(p, c)
+		if best == nil || scores[i] > scores[bestAt] {
+			best, bestAt = c, i
+		}
+	}
+	return best, scores
+}
+
+func cluster(p *points, opts ClusterOptions) *Clustering {
+	k := opts.K
+	if k > len(p.x) {
+		k = len(p.x)
+	}
+	if k < 1 {
+		return &Clustering{Options: opts, Assignments: map[int]int{}}
+	}
+	rng := rand.New(rand.NewSource(opts.Seed))
+	seeds := seed(p, k, rng)
+	assign := make([]int, len(p.x))
+
+	var c *Clustering
+	if opts.Method == KMedoids {
+		c = kMedoids(p, seeds, assign, opts.Iterations)
+	} else {
+		c = kMeans(p, seeds, assign, opts.Iterations)
+	}
+	c.Options = opts
+	c.Assignments = make(map[int]int, len(p.ids))
+	c.Members = make([][]int, k)
+	for i, id := range p.ids {
+		c.Assignments[id] = assign[i]
+		c.Members[assign[i]] = append(c.Members[assign[i]], id)
+	}
+	return c
+}
+
+// seed is k-means++, the first centre is drawn uniformly and each next one with
+// probability proportional to its squared distance from the nearest centre so far
+func seed(p *points, k int, rng *rand.Rand) []int {
+	seeds := []int{rng.Intn(len(p.x))}
+	nearest := make([]float64, len(p.x))
+	for i := range nearest {
+		nearest[i] = math.Inf(1)
+	}
+	for len(seeds) < k {
+		last := p.x[seeds[len(seeds)-1]]
+		total := 0.0
+		for i := range p.x {
+			if d := distance(p.x[i], last); d*d < nearest[i] {
+				nearest[i] = d * d
+			}
+			total += nearest[i]
+		}
+		next := len(p.x) - 1
+		if total == 0 {
+			next = rng.Intn(len(p.x))
+		} else {
+			target := rng.Float64() * total
+			for i, d := range nearest {
+				if target -= d; target < 0 {
+					next = i
+					break
+				}
+			}
+		}
+		seeds = append(seeds, next)
+	}
+	return seeds
+}
+
+// nearestCentre is the index of the centre closest to v
+func nearestCentre(v []float64, centres [][]float64) int {
+	best, bestDist := 0, math.Inf(1)
+	for c, centre := range centres {
+		if d := distance(v, centre); d < bestDist {
+			best, bestDist = c, d
+		}
+	}
+	return best
+}
+
+func kMeans(p *points, seeds []int, assign []int, iterations int) *Clustering {
+	centres := make([][]float64, len(seeds))
+	for c, i := range seeds {
+		centres[c] = append([]float64(nil), p.x[i]...)
+	}
+	for i := range assign {
+		assign[i] = -1
+	}
+
+	for it := 0; it < iterations; it++ {
+		changed := false
+		for i, v := range p.x {
+			if c := nearestCentre(v, centres); c != assign[i] {
+				assign[i], changed = c, true
+			}
+		}
+		if !changed {
+			break
+		}
+
+		counts := make([]int, len(centres))
+		for c := range centres {
+			for d := range centres[c] {
+				centres[c][d] = 0
+			}
+		}
+		for i, v := range p.x {
+			counts[assign[i]]++
+			for d, x := range v {
+				centres[assign[i]][d] += x
+			}
+		}
+		for c := range centres {
+			for d := range centres[c] {
+				centres[c][d] = ratio(centres[c][d], float64(counts[c]))
+			}
+		}
+		for c := range centres {
+			if counts[c] == 0 {
+				// an emptied cluster restarts on the point furthest from its centre
+				far := farthest(p, centres, assign)
+				counts[assign[far]]--
+				copy(centres[c], p.x[far])
+				assign[far], counts[c] = c, 1
+			}
+		}
+	}
+	return &Clustering{Centroids: centres}
+}
+
+func farthest(p *points, centres [][]float64, assign []int) int {
+	far, farDist := 0, -1.0
+	for i, v := range p.x {
+		if d := distance(v, centres[assign[i]]); d > farDist {
+			far, farDist = i, d
+		}
+	}
+	return far
+}
+
+// kMedoids alternates assigning every point to its nearest medoid with moving each
+// medoid to the member closest in total to the rest of its cluster
+func kMedoids(p *points, medoids []int, assign []int, iterations int) *Clustering {
+	dist := p.distances()
+	for it := 0; it < iterations; it++ {
+		for i := range p.x {
+			best := 0
+			for c, m := range medoids {
+				if dist[i][m] < dist[i][medoids[best]] {
+					best = c
+				}
+			}
+			assign[i] = best
+		}
+
+		changed := false
+		for c := range medoids {
+			best, bestCost := medoids[c], math.Inf(1)
+			for i := range p.x {
+				if assign[i] != c {
+					continue
+				}
+				cost := 0.0
+				for j := range p.x {
+					if assign[j] == c {
+						cost += dist[i][j]
+					}
+				}
+				if cost < bestCost {
+					best, bestCost = i, cost
+				}
+			}
+			if best != medoids[c] {
+				medoids[c], changed = best, true
+			}
+		}
+		if !changed {
+			break
+		}
+	}
+
+	c := &Clustering{}
+	for _, m := range medoids {
+		c.Medoids = append(c.Medoids, p.ids[m])
+		c.Centroids = append(c.Centroids, append([]float64(nil), p.x[m]...))
+	}
+	return c
+}
+
+// Silhouette is the mean over every vector of how much nearer it is to its own
+// cluster than to the next nearest, from -1 to 1 with higher meaning better separated
+func Silhouette(vectors map[int][]float64, c *Clustering) float64 {
+	return silhouette(newPoints(vectors), c)
+}
+
+// silhouette leaves points of single member clusters at zero, the usual convention
+func silhouette(p *points, c *Clustering) float64 {
+	if len(c.Members) < 2 || len(p.x) == 0 {
+		return 0.0
+	}
+	dist := p.distances()
+	total := 0.0
+	for i, id := range p.ids {
+		own, ok := c.Assignments[id]
+		if !ok || len(c.Members[own]) < 2 {
+			continue
+		}
+		sums := make([]float64, len(c.Members))
+		for j, other := range p.ids {
+			if cl, ok := c.Assignments[other]; ok && j != i {
+				sums[cl] += dist[i][j]
+			}
+		}
+		a := sums[own] / float64(len(c.Members[own])-1)
+		b := math.Inf(1)
+		for cl, sum := range sums {
+			if cl != own && len(c.Members[cl]) > 0 {
+				b = math.Min(b, sum/float64(len(c.Members[cl])))
+			}
+		}
+		if larger := math.Max(a, b); larger > 0 && !math.IsInf(b, 1) {
+			total += (b - a) / larger
+		}
+	}
+	return total / float64(len(p.ids))
+}
+
+// Peers is the other users in userID's cluster, ok is false for an unclustered user
+func (c *Clustering) Peers(userID int) ([]int, bool) {
+	cl, ok := c.Assignments[userID]
+	if !ok {
+		return nil, false
+	}
+	return c.Members[cl], true
+}
+
+// Name labels every cluster by the two genres its users rate furthest above how
+// everyone rates them, measured on ratings less each user's mean
+func (c *Clustering) Name(users Users, movies Movies) {
+	genres := make(map[int][GenreCount]bool, len(movies))
+	for _, m := range movies {
+		genres[m.ID] = m.Genres
+	}
+	m := NewMatrix(users)
+
+	// sums[k][g] and counts[k][g] are per cluster, the last row is everyone
+	sums := make([][GenreCount]float64, len(c.Members)+1)
+	counts := make([][GenreCount]int, len(c.Members)+1)
+	for _, userID := range m.UserIDs() {
+		row, _ := m.UserRow(userID)
+		mean := m.UserMean(userID)
+		cl, clustered := c.Assignments[userID]
+		for i, movieID := range row.Index {
+			for g, ok := range genres[movieID] {
+				if !ok {
+					continue
+				}
+				sums[len(c.Members)][g] += row.Value[i] - mean
+				counts[len(c.Members)][g]++
+				if clustered {
+					sums[cl][g] += row.Value[i] - mean
+					counts[cl][g]++
+				}
+			}
+		}
+	}
+
+	c.Names = make([]string, len(c.Members))
+	c.Genres = make([][]string, len(c.Members))
+	used := make(map[string]int)
+	for cl := range c.Members {
+		c.Genres[cl] = c.lift(cl, sums, counts)
+		name := strings.Join(c.Genres[cl], " & ")
+		if name == "" {
+			name = "General"
+		}
+		if used[name]++; used[name] > 1 {
+			name = fmt.Sprintf("%s %d", name, used[name])
+		}
+		c.Names[cl] = name
+	}
+}
+
+// segmentShrinkage pulls a cluster's lift for a genre towards zero as if it had this
+// many more ratings, so a small cluster is not named after one lucky movie
+const segmentShrinkage = 50
+
+// lift is the cluster's top two genres by how much more its users like them than
+// everyone does, genres it likes less and "unknown" are left out
+func (c *Clustering) lift(cl int, sums [][GenreCount]float64, counts [][GenreCount]int) []string {
+	all := len(c.Members)
+	type genreLift struct {
+		genre int
+		lift  float64
+	}
+	var lifts []genreLift
+	for g := 1; g < GenreCount; g++ {
+		overall := ratio(sums[all][g], float64(counts[all][g]))
+		l := (sums[cl][g] - overall*float64(counts[cl][g])) / (float64(counts[cl][g]) + segmentShrinkage)
+		if l > 0 {
+			lifts = append(lifts, genreLift{g, l})
+		}
+	}
+	sort.Slice(lifts, func(i, j int) bool { return lifts[i].lift > lifts[j].lift })
+
+	var names []string
+	for i := 0; i < len(lifts) && i < 2; i++ {
+		names = append(names, GenreNames[lifts[i].genre])
+	}
+	return names
+}
+
+// Segments lists every cluster with its name and size
+func (c *Clustering) Segments() []Segment {
+	segments := make([]Segment, len(c.Members))
+	for cl := range c.Members {
+		segments[cl] = c.segment(cl)
+	}
+	return segments
+}
+
+// Segment is the segment the user belongs to
+func (c *Clustering) Segment(userID int) (Segment, bool) {
+	cl, ok := c.Assignments[userID]
+	if !ok {
+		return Segment{}, false
+	}
+	return c.segment(cl), true
+}
+
+func (c *Clustering) segment(cl int) Segment {
+	s := Segment{ID: cl, Size: len(c.Members[cl])}
+	if cl < len(c.Names) {
+		s.Name, s.Genres = c.Names[cl], c.Genres[cl]
+	}
+	return s
+}
@@ -0,0 +1,200 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Config names a recommender and its parameters so it can be saved and rebuilt
+type Config struct {
+	Algorithm      string  `json:"algorithm"`
+	Metric         string  `json:"metric,omitempty"`
+	Significance   int     `json:"significance,omitempty"`
+	Neighbours     int     `json:"neighbours,omitempty"`
+	MinSupport     int     `json:"minSupport,omitempty"`
+	MinRaters      int     `json:"minRaters,omitempty"`
+	Shrinkage      float64 `json:"shrinkage,omitempty"`
+	Solver         string  `json:"solver,omitempty"`
+	Factors        int     `json:"factors,omitempty"`
+	LearningRate   float64 `json:"learningRate,omitempty"`
+	Regularization float64 `json:"regularization,omitempty"`
+	Epochs         int     `json:"epochs,omitempty"`
+	Seed           int64   `json:"seed,omitempty"`
+	HalfLife       float64 `json:"halfLifeDays,omitempty"`
+	Window         float64 `json:"windowDays,omitempty"`
+	TimeBins       int     `json:"timeBins,omitempty"`
+	BiPolar        bool    `json:"biPolar,omitempty"`
+	Clusters       int     `json:"clusters,omitempty"`
+	History        int     `json:"history,omitempty"`
+	Decay          float64 `json:"decay,omitempty"`
+}
+
+// Algorithm names understood by Config.Build. Each builds a recommender SaveModel can
+// store, BPR, Hybrid, ContentBased and the baselines have no name so Validate refuses
+// a config for them before LoadOrTrain trains something it could not save
+const (
+	AlgorithmUser          = "user"
+	AlgorithmItem          = "item"
+	AlgorithmFactorization = "mf"
+	AlgorithmSlopeOne      = "slopeone"
+	AlgorithmMarkov        = "markov"
+)
+
+// LoadConfig reads a JSON config written by Save
+func LoadConfig(path string) (Config, error) {
+	var c Config
+	body, err := os.ReadFile(path)
+	if err != nil {
+		return c, err
+	}
+	if err := json.Unmarshal(body, &c); err != nil {
+		return c, fmt.Errorf("could not parse config %s: %w", path, err)
+	}
+	return c, nil
+}
+
+// Save writes the config to path as indented JSON
+func (c Config) Save(path string) error {
+	body, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(body, '\n'), 0644)
+}
+
+// Validate reports names in the config that Build does not understand
+func (c Config) Validate() error {
+	switch c.Algorithm {
+	case AlgorithmUser, AlgorithmItem:
+		_, err := SimilarityByName(c.Metric)
+		return err
+	case AlgorithmFactorization:
+		if c.Solver != "" && c.Solver != "sgd" && c.Solver != "als" {
+			return fmt.Errorf("unknown solver %q", c.Solver)
+		}
+		return nil
+	case AlgorithmSlopeOne, AlgorithmMarkov:
+		return nil
+	}
+	return fmt.Errorf("unknown algorithm %q", c.Algorithm)
+}
+
+// Build trains the configured recommender on users
+func (c Config) Build(users Users) (Recommender, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	switch c.Algorithm {
+	case AlgorithmUser, AlgorithmItem:
+		metric, err := c.similarity()
+		if err != nil {
+			return nil, err
+		}
+		if c.Algorithm == AlgorithmUser {
+			ub := NewUserBased(users, metric, c.userBasedOptions())
+			ub.Clusters = c.clustering(users)
+			return ub, nil
+		}
+		ib := NewItemBased(users, metric, c.Neighbours)
+		ib.Time, ib.MinSupport, ib.Shrinkage = c.timeWeight(), c.MinSupport, c.Shrinkage
+		return ib, nil
+	case AlgorithmFactorization:
+		return NewFactorization(users, c.factorizationConfig()), nil
+	case AlgorithmSlopeOne:
+		return NewSlopeOne(users, c.BiPolar), nil
+	case AlgorithmMarkov:
+		return NewMarkov(users, c.markovOptions()), nil
+	}
+	return nil, fmt.Errorf("unknown algorithm %q", c.Algorithm)
+}
+
+func (c Config) userBasedOptions() UserBasedOptions {
+	return UserBasedOptions{
+		Neighbours: c.Neighbours,
+		MinSupport: c.MinSupport,
+		MinRaters:  c.MinRaters,
+		Shrinkage:  c.Shrinkage,
+		Time:       c.timeWeight(),
+	}
+}
+
+// clustering groups users by k-means over their rating vectors, nil when Clusters is 0.
+// The clusters only restrict neighbours, they are left unnamed because a config
+// carries no movies to name them after; call Name with the catalogue to report them
+func (c Config) clustering(users Users) *Clustering {
+	if c.Clusters == 0 {
+		return nil
+	}
+	opts := DefaultClustering
+	opts.K = c.Clusters
+	return Cluster(RatingVectors(users), opts)
+}
+
+// factorizationConfig takes Factors, LearningRate, Regularization and Epochs from
+// DefaultFactorization when they are unset, Seed and TimeBins are taken as given
+func (c Config) factorizationConfig() FactorizationConfig {
+	fc := DefaultFactorization
+	fc.Seed, fc.TimeBins = c.Seed, c.TimeBins
+	if c.Solver == "als" {
+		fc.Solver = ALS
+	}
+	if c.Factors > 0 {
+		fc.Factors = c.Factors
+	}
+	if c.LearningRate > 0 {
+		fc.LearningRate = c.LearningRate
+	}
+	if c.Regularization > 0 {
+		fc.Regularization = c.Regularization
+	}
+	if c.Epochs > 0 {
+		fc.Epochs = c.Epochs
+	}
+	return fc
+}
+
+// markovOptions takes History and Decay from DefaultMarkov when they are unset and
+// always keeps its smoothing
+func (c Config) markovOptions() MarkovOptions {
+	opts := DefaultMarkov
+	if c.History > 0 {
+		opts.History = c.History
+	}
+	if c.Decay > 0 {
+		opts.Decay = c.Decay
+	}
+	return opts
+}
+
+func (c Config) timeWeight() TimeWeight {
+	return TimeWeight{HalfLife: Days(c.HalfLife), Window: Days(c.Window)}
+}
+
+func (c Config) similarity() (Similarity, error) {
+	metric, err := SimilarityByName(c.Metric)
+	if err != nil {
+		return nil, err
+	}
+	if c.Significance > 0 {
+		metric = Significance{Metric: metric, Threshold: c.Significance}
+	}
+	return metric, nil
+}
+
+// SimilarityByName maps "cosine", "pearson", "adjusted", "jaccard" and "spearman" to their metric
+func SimilarityByName(name string) (Similarity, error) {
+	switch name {
+	case "", "cosine":
+		return Cosine{}, nil
+	case "pearson":
+		return Pearson{}, nil
+	case "adjusted":
+		return AdjustedCosine{}, nil
+	case "jaccard":
+		return Jaccard{}, nil
+	case "spearman":
+		return Spearman{}, nil
+	}
+	return nil, fmt.Errorf("unknown similarity %q", name)
+}
@@ -0,0 +1,208 @@
+package ai
+
+import (
+	"math"
+	"sync"
+)
+
+// ContentBased recommends movies whose genres match a taste profile built from the
+// user's own ratings, so it needs no overlap with other users
+type ContentBased struct {
+	Features map[int][]float64
+	Profiles map[int][]float64
+	movieIDs []int
+	matrix   *Matrix
+	mu       sync.RWMutex // written by AddRating and RemoveRating, read by everything else
+}
+
+// GenreVector is the movie's genre flags as 0 or 1
+func (m Movie) GenreVector() []float64 {
+	v := make([]float64, GenreCount)
+	for i, ok := range m.Genres {
+		if ok {
+			v[i] = 1
+		}
+	}
+	return v
+}
+
+// NewContentBased weights each genre flag by how rare the genre is in movies and
+// builds a profile for every user in users
+func NewContentBased(users Users, movies Movies) *ContentBased {
+	cb := &ContentBased{
+		Features: make(map[int][]float64, len(movies)),
+		Profiles: make(map[int][]float64, len(users)),
+		matrix:   NewMatrix(users),
+	}
+
+	var counts [GenreCount]int
+	for _, m := range movies {
+		for g, ok := range m.Genres {
+			if ok {
+				counts[g]++
+			}
+		}
+	}
+	var weights [GenreCount]float64
+	for g, n := range counts {
+		if n > 0 {
+			weights[g] = math.Log(float64(len(movies)) / float64(n))
+		}
+	}
+
+	for _, m := range movies {
+		v := m.GenreVector()
+		for g := range v {
+			v[g] *= weights[g]
+		}
+		cb.Features[m.ID] = v
+		cb.movieIDs = append(cb.movieIDs, m.ID)
+	}
+	for _, userID := range cb.matrix.UserIDs() {
+		cb.Profiles[userID] = cb.profile(userID)
+	}
+	return cb
+}
+
+// profile sums the features of the user's movies weighted by how far each rating is
+// from the user's mean, so disliked genres end up negative
+func (cb *ContentBased) profile(userID int) []float64 {
+	row, _ := cb.matrix.UserRow(userID)
+	userMean := cb.matrix.UserMean(userID)
+	p := make([]float64, GenreCount)
+	for i, movieID := range row.Index {
+		features, ok := cb.Features[movieID]
+		if !ok {
+			continue
+		}
+		for g := range p {
+			p[g] += (row.Value[i] - userMean) * features[g]
+		}
+	}
+	return p
+}
+
+// Augment returns the training ratings with each user's extra best matching unrated
+// movies added at their predicted scores
+func (cb *ContentBased) Augment(extra int) Users {
+	users := cb.matrix.Users()
+	for i, user := range users {
+		for _, r := range cb.TopN(user.ID, extra) {
+			if score, ok := cb.Predict(user.ID, r.MovieID); ok {
+				users[i].Ratings = append(users[i].Ratings, Rating{MovieID: r.MovieID, Score: score})
+			}
+		}
+	}
+	return users
+}
+
+// AddRating updates the matrix and rebuilds the user's profile
+func (cb *ContentBased) AddRating(userID int, r Rating) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	cb.matrix.AddRating(userID, r)
+	cb.Profiles[userID] = cb.profile(userID)
+}
+
+// RemoveRating is AddRating for a deleted rating
+func (cb *ContentBased) RemoveRating(userID, movieID int) bool {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	if !cb.matrix.RemoveRating(userID, movieID) {
+		return false
+	}
+	if cb.matrix.HasUser(userID) {
+		cb.Profiles[userID] = cb.profile(userID)
+	} else {
+		delete(cb.Profiles, userID)
+	}
+	return true
+}
+
+// Match is the cosine between the user's profile and the movie's features
+func (cb *ContentBased) Match(userID, movieID int) (float64, bool) {
+	cb.mu.RLock()
+	defer cb.mu.RUnlock()
+	return cb.match(userID, movieID)
+}
+
+func (cb *ContentBased) match(userID, movieID int) (float64, bool) {
+	p, ok := cb.Profiles[userID]
+	if !ok {
+		return 0.0, false
+	}
+	features, ok := cb.Features[movieID]
+	if !ok {
+		return 0.0, false
+	}
+	return ratio(dot(p, features), norm(p, 0)*norm(features, 0)), true
+}
+
+// Similar returns up to n movies with the closest genres to movieID
+func (cb *ContentBased) Similar(movieID, n int) []Neighbour {
+	cb.mu.RLock()
+	defer cb.mu.RUnlock()
+	features, ok := cb.Features[movieID]
+	if !ok {
+		return nil
+	}
+	var list []Neighbour
+	for _, id := range cb.movieIDs {
+		if id == movieID {
+			continue
+		}
+		other := cb.Features[id]
+		if sim := ratio(dot(features, other), norm(features, 0)*norm(other, 0)); sim > 0 {
+			list = append(list, Neighbour{ID: id, Similarity: sim})
+		}
+	}
+	return topNeighbours(list, n)
+}
+
+// Predict is the average of the user's ratings weighted by each rated movie's genre
+// similarity to movieID
+func (cb *ContentBased) Predict(userID, movieID int) (float64, bool) {
+	cb.mu.RLock()
+	defer cb.mu.RUnlock()
+	features, ok := cb.Features[movieID]
+	if !ok {
+		return 0.0, false
+	}
+	row, _ := cb.matrix.UserRow(userID)
+	sum, weights := 0.0, 0.0
+	for i, ratedID := range row.Index {
+		other, ok := cb.Features[ratedID]
+		if !ok {
+			continue
+		}
+		if sim := ratio(dot(features, other), norm(features, 0)*norm(other, 0)); sim > 0 {
+			sum += sim * row.Value[i]
+			weights += sim
+		}
+	}
+	if weights == 0 {
+		return 0.0, false
+	}
+	return sum / weights, true
+}
+
+// TopN ranks the movies the user has not rated by how well they match the profile,
+// the scores are cosines rather than ratings
+func (cb *ContentBased) TopN(userID, n int) []Rating {
+	cb.mu.RLock()
+	defer cb.mu.RUnlock()
+	row, ok := cb.matrix.UserRow(userID)
+	if !ok {
+		return coldStart(cb.matrix, userID, n)
+	}
+	var recs []Rating
+	for _, movieID := range cb.movieIDs {
+		if _, ok := row.At(movieID); ok {
+			continue
+		}
+		if score, _ := cb.match(userID, movieID); score > 0 {
+			recs = append(recs, Rating{MovieID: movieID, Score: score})
+		}
+	}
+	return topRatings(recs, n)
+}
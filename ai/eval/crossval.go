@@ -0,0 +1,152 @@
+package eval
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"text/tabwriter"
+
+	"golearn/ai"
+)
+
+// Metric names one of the numbers in a Result
+type Metric string
+
+const (
+	MetricRMSE      Metric = "RMSE"
+	MetricMAE       Metric = "MAE"
+	MetricPrecision Metric = "precision"
+	MetricRecall    Metric = "recall"
+	MetricMAP       Metric = "MAP"
+	MetricNDCG      Metric = "NDCG"
+	MetricHitRate   Metric = "hitRate"
+	MetricAUC       Metric = "AUC"
+)
+
+// Metrics lists every metric reported for a Result
+var Metrics = []Metric{MetricRMSE, MetricMAE, MetricPrecision, MetricRecall, MetricMAP, MetricNDCG, MetricHitRate, MetricAUC}
+
+// LowerIsBetter is true for the rating error metrics
+func (m Metric) LowerIsBetter() bool {
+	return m == MetricRMSE || m == MetricMAE
+}
+
+// Value reads metric m from the result
+func (r Result) Value(m Metric) float64 {
+	switch m {
+	case MetricRMSE:
+		return r.RMSE
+	case MetricMAE:
+		return r.MAE
+	case MetricPrecision:
+		return r.Precision
+	case MetricRecall:
+		return r.Recall
+	case MetricMAP:
+		return r.MAP
+	case MetricNDCG:
+		return r.NDCG
+	case MetricHitRate:
+		return r.HitRate
+	case MetricAUC:
+		return r.AUC
+	}
+	return math.NaN()
+}
+
+// Summary is the spread of one model's results over several folds
+type Summary struct {
+	Name     string
+	Folds    []Result
+	Mean     map[Metric]float64
+	Variance map[Metric]float64
+}
+
+// KFold shuffles all ratings and deals them into k folds, each split tests on one fold
+// and trains on the rest
+func KFold(users ai.Users, k int, seed int64) ([]Split, error) {
+	if k < 2 {
+		return nil, fmt.Errorf("k-fold needs at least 2 folds, got %d", k)
+	}
+	type ref struct{ user, rating int }
+	var refs []ref
+	for u, user := range users {
+		for r := range user.Ratings {
+			refs = append(refs, ref{u, r})
+		}
+	}
+	rng := rand.New(rand.NewSource(seed))
+	rng.Shuffle(len(refs), func(i, j int) {
+		refs[i], refs[j] = refs[j], refs[i]
+	})
+
+	folds := make([][]int, len(users))
+	for u, user := range users {
+		folds[u] = make([]int, len(user.Ratings))
+	}
+	for i, ref := range refs {
+		folds[ref.user][ref.rating] = i % k
+	}
+
+	splits := make([]Split, k)
+	for fold := range splits {
+		splits[fold] = partition(users, func(u int, user ai.User) []bool {
+			test := make([]bool, len(user.Ratings))
+			for r := range test {
+				test[r] = folds[u][r] == fold
+			}
+			return test
+		})
+	}
+	return splits, nil
+}
+
+// CrossValidate evaluates model on every split and summarises the results
+func CrossValidate(model Model, splits []Split, opts Options) Summary {
+	summary := Summary{
+		Name:     model.Name,
+		Mean:     make(map[Metric]float64),
+		Variance: make(map[Metric]float64),
+	}
+	for _, split := range splits {
+		summary.Folds = append(summary.Folds, Evaluate(model, split, opts))
+	}
+	if len(summary.Folds) == 0 {
+		return summary
+	}
+
+	n := float64(len(summary.Folds))
+	for _, m := range Metrics {
+		mean := 0.0
+		for _, r := range summary.Folds {
+			mean += r.Value(m)
+		}
+		mean /= n
+		variance := 0.0
+		for _, r := range summary.Folds {
+			variance += (r.Value(m) - mean) * (r.Value(m) - mean)
+		}
+		summary.Mean[m] = mean
+		summary.Variance[m] = variance / n
+	}
+	return summary
+}
+
+// WriteSummaries prints the mean and standard deviation of every metric, one row per model
+func WriteSummaries(w io.Writer, summaries []Summary) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprint(tw, "model\t")
+	for _, m := range Metrics {
+		fmt.Fprintf(tw, "%s\t", m)
+	}
+	fmt.Fprintln(tw)
+	for _, s := range summaries {
+		fmt.Fprintf(tw, "%s\t", s.Name)
+		for _, m := range Metrics {
+			fmt.Fprintf(tw, "%.4f ± %.2g\t", s.Mean[m], math.Sqrt(s.Variance[m]))
+		}
+		fmt.Fprintln(tw)
+	}
+	return tw.Flush()
+}
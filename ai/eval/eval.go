@@ -0,0 +1,170 @@
+package eval
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"text/tabwriter"
+	"time"
+
+	"golearn/ai"
+)
+
+// Model trains a recommender on the training users of a split
+type Model struct {
+	Name  string
+	Train func(train ai.Users) ai.Recommender
+}
+
+// Options controls how a trained recommender is scored
+type Options struct {
+	K         int     // length of the recommendation lists
+	Threshold float64 // test ratings at or above this are relevant
+	MaxUsers  int     // only rank for the first MaxUsers test users, 0 means all
+}
+
+// DefaultOptions scores top 10 lists against test ratings of 4 or more
+var DefaultOptions = Options{K: 10, Threshold: 4}
+
+// Result holds every metric for one model on one split
+type Result struct {
+	Name      string
+	RMSE      float64
+	MAE       float64
+	Coverage  float64 // share of test ratings the model could predict
+	Precision float64
+	Recall    float64
+	MAP       float64
+	NDCG      float64
+	HitRate   float64
+	AUC       float64 // only for an ai.Scorer, over the movies rated in training
+	Users     int     // users with at least one relevant test rating
+	Duration  time.Duration
+}
+
+// Evaluate trains model on split.Train and scores it on split.Test, rating error is
+// only reported when the recommender is also an ai.Predictor and AUC when it is an
+// ai.Scorer
+func Evaluate(model Model, split Split, opts Options) Result {
+	start := time.Now()
+	rec := model.Train(split.Train)
+	result := Result{Name: model.Name, RMSE: math.NaN(), MAE: math.NaN(), AUC: math.NaN()}
+
+	if predictor, ok := rec.(ai.Predictor); ok {
+		var predicted, actual []float64
+		total := 0
+		for _, user := range split.Test {
+			for _, r := range user.Ratings {
+				total++
+				if score, ok := predictor.Predict(user.ID, r.MovieID); ok {
+					predicted = append(predicted, score)
+					actual = append(actual, r.Score)
+				}
+			}
+		}
+		result.RMSE = RMSE(predicted, actual)
+		result.MAE = MAE(predicted, actual)
+		if total > 0 {
+			result.Coverage = float64(len(actual)) / float64(total)
+		}
+	}
+
+	scorer, scored := rec.(ai.Scorer)
+	var train *ai.Matrix
+	if scored {
+		train = ai.NewMatrix(split.Train)
+	}
+	aucSum, aucUsers := 0.0, 0
+
+	for _, user := range split.Test {
+		if opts.MaxUsers > 0 && result.Users >= opts.MaxUsers {
+			break
+		}
+		relevant := make(map[int]bool)
+		for _, r := range user.Ratings {
+			if r.Score >= opts.Threshold {
+				relevant[r.MovieID] = true
+			}
+		}
+		if len(relevant) == 0 {
+			continue
+		}
+
+		var recommended []int
+		for _, r := range rec.TopN(user.ID, opts.K) {
+			recommended = append(recommended, r.MovieID)
+		}
+		result.Precision += PrecisionAtK(recommended, relevant, opts.K)
+		result.Recall += RecallAtK(recommended, relevant, opts.K)
+		result.MAP += AveragePrecisionAtK(recommended, relevant, opts.K)
+		result.NDCG += NDCGAtK(recommended, relevant, opts.K)
+		result.HitRate += HitRateAtK(recommended, relevant, opts.K)
+		result.Users++
+
+		if scored {
+			if auc := userAUC(scorer, train, user, relevant); !math.IsNaN(auc) {
+				aucSum += auc
+				aucUsers++
+			}
+		}
+	}
+	if aucUsers > 0 {
+		result.AUC = aucSum / float64(aucUsers)
+	}
+	if result.Users > 0 {
+		n := float64(result.Users)
+		result.Precision /= n
+		result.Recall /= n
+		result.MAP /= n
+		result.NDCG /= n
+		result.HitRate /= n
+	}
+
+	result.Duration = time.Since(start)
+	return result
+}
+
+// userAUC scores the user's relevant test movies against every training movie they
+// rated neither in training nor in test
+func userAUC(scorer ai.Scorer, train *ai.Matrix, user ai.User, relevant map[int]bool) float64 {
+	rated, _ := train.UserRow(user.ID)
+	tested := make(map[int]bool, len(user.Ratings))
+	for _, r := range user.Ratings {
+		tested[r.MovieID] = true
+	}
+
+	var positives, negatives []float64
+	for _, movieID := range train.MovieIDs() {
+		score, ok := scorer.Score(user.ID, movieID)
+		if !ok {
+			continue
+		}
+		if relevant[movieID] {
+			positives = append(positives, score)
+		} else if _, seen := rated.At(movieID); !seen && !tested[movieID] {
+			negatives = append(negatives, score)
+		}
+	}
+	return AUC(positives, negatives)
+}
+
+// Compare evaluates every model on the same split
+func Compare(models []Model, split Split, opts Options) []Result {
+	results := make([]Result, 0, len(models))
+	for _, model := range models {
+		results = append(results, Evaluate(model, split, opts))
+	}
+	return results
+}
+
+// WriteTable prints results as aligned columns, one row per model
+func WriteTable(w io.Writer, results []Result, k int) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "model\tRMSE\tMAE\tcoverage\tP@%d\tR@%d\tMAP@%d\tNDCG@%d\tHR@%d\tAUC\tusers\ttime\t\n", k, k, k, k, k)
+	for _, r := range results {
+		fmt.Fprintf(tw, "%s\t%.4f\t%.4f\t%.3f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%.4f\t%d\t%s\t\n",
+			r.Name, r.RMSE, r.MAE, r.Coverage, r.Precision, r.Recall, r.MAP, r.NDCG, r.HitRate, r.AUC,
+			r.Users, r.Duration.Round(time.Millisecond))
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,121 @@
+package eval
+
+import (
+	"math"
+	"sort"
+)
+
+// RMSE is the root mean squared difference between predictions and actual scores
+func RMSE(predicted, actual []float64) float64 {
+	if len(actual) == 0 {
+		return math.NaN()
+	}
+	sum := 0.0
+	for i := range actual {
+		sum += (predicted[i] - actual[i]) * (predicted[i] - actual[i])
+	}
+	return math.Sqrt(sum / float64(len(actual)))
+}
+
+// MAE is the mean absolute difference between predictions and actual scores
+func MAE(predicted, actual []float64) float64 {
+	if len(actual) == 0 {
+		return math.NaN()
+	}
+	sum := 0.0
+	for i := range actual {
+		sum += math.Abs(predicted[i] - actual[i])
+	}
+	return sum / float64(len(actual))
+}
+
+// AUC is the chance that a random positive scores above a random negative, ties
+// count half
+func AUC(positives, negatives []float64) float64 {
+	if len(positives) == 0 || len(negatives) == 0 {
+		return math.NaN()
+	}
+	sorted := append([]float64(nil), negatives...)
+	sort.Float64s(sorted)
+	sum := 0.0
+	for _, p := range positives {
+		below := sort.SearchFloat64s(sorted, p)
+		ties := sort.Search(len(sorted), func(i int) bool { return sorted[i] > p }) - below
+		sum += float64(below) + float64(ties)/2
+	}
+	return sum / (float64(len(positives)) * float64(len(negatives)))
+}
+
+// PrecisionAtK is the share of the first k recommendations that are relevant
+func PrecisionAtK(recommended []int, relevant map[int]bool, k int) float64 {
+	if k <= 0 {
+		return 0.0
+	}
+	return float64(hits(recommended, relevant, k)) / float64(k)
+}
+
+// RecallAtK is the share of the relevant movies found in the first k recommendations
+func RecallAtK(recommended []int, relevant map[int]bool, k int) float64 {
+	if len(relevant) == 0 {
+		return 0.0
+	}
+	return float64(hits(recommended, relevant, k)) / float64(len(relevant))
+}
+
+// AveragePrecisionAtK averages the precision at every relevant position within the first k
+func AveragePrecisionAtK(recommended []int, relevant map[int]bool, k int) float64 {
+	if len(relevant) == 0 {
+		return 0.0
+	}
+	found, sum := 0, 0.0
+	for i, movieID := range cutoff(recommended, k) {
+		if relevant[movieID] {
+			found++
+			sum += float64(found) / float64(i+1)
+		}
+	}
+	return sum / math.Min(float64(len(relevant)), float64(k))
+}
+
+// NDCGAtK is the discounted gain of the first k recommendations over the best possible gain
+func NDCGAtK(recommended []int, relevant map[int]bool, k int) float64 {
+	dcg := 0.0
+	for i, movieID := range cutoff(recommended, k) {
+		if relevant[movieID] {
+			dcg += 1 / math.Log2(float64(i+2))
+		}
+	}
+	ideal := 0.0
+	for i := 0; i < len(relevant) && i < k; i++ {
+		ideal += 1 / math.Log2(float64(i+2))
+	}
+	if ideal == 0 {
+		return 0.0
+	}
+	return dcg / ideal
+}
+
+// HitRateAtK is 1 when any of the first k recommendations is relevant
+func HitRateAtK(recommended []int, relevant map[int]bool, k int) float64 {
+	if hits(recommended, relevant, k) > 0 {
+		return 1.0
+	}
+	return 0.0
+}
+
+func hits(recommended []int, relevant map[int]bool, k int) int {
+	n := 0
+	for _, movieID := range cutoff(recommended, k) {
+		if relevant[movieID] {
+			n++
+		}
+	}
+	return n
+}
+
+func cutoff(recommended []int, k int) []int {
+	if k < len(recommended) {
+		return recommended[:k]
+	}
+	return recommended
+}
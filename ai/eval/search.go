@@ -0,0 +1,241 @@
+package eval
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+
+	"golearn/ai"
+)
+
+// Grid lists the values to try for each parameter, empty lists keep the Base value
+type Grid struct {
+	Base           ai.Config
+	Algorithms     []string
+	Metrics        []string
+	Significance   []int
+	Neighbours     []int
+	MinSupport     []int
+	MinRaters      []int
+	Shrinkage      []float64
+	Solvers        []string
+	Factors        []int
+	LearningRates  []float64
+	Regularization []float64
+	Epochs         []int
+	HalfLives      []float64
+	Windows        []float64
+	TimeBins       []int
+	BiPolar        []bool
+	Clusters       []int
+	History        []int
+	Decay          []float64
+}
+
+// Trial is one configuration and how it did across the folds, Err is set instead
+// when the configuration could not be built
+type Trial struct {
+	Config  ai.Config
+	Summary Summary
+	Err     error
+}
+
+// unbuilt stands in for a config that failed to build, it recommends nothing
+type unbuilt struct{}
+
+func (unbuilt) TopN(userID, n int) []ai.Rating { return nil }
+
+// Configs returns every combination in the grid, combinations that only differ in
+// parameters their algorithm ignores are returned once
+func (g Grid) Configs() []ai.Config {
+	configs := []ai.Config{g.Base}
+	configs = expand(configs, len(g.Algorithms), func(c *ai.Config, i int) { c.Algorithm = g.Algorithms[i] })
+	configs = expand(configs, len(g.Metrics), func(c *ai.Config, i int) { c.Metric = g.Metrics[i] })
+	configs = expand(configs, len(g.Significance), func(c *ai.Config, i int) { c.Significance = g.Significance[i] })
+	configs = expand(configs, len(g.Neighbours), func(c *ai.Config, i int) { c.Neighbours = g.Neighbours[i] })
+	configs = expand(configs, len(g.MinSupport), func(c *ai.Config, i int) { c.MinSupport = g.MinSupport[i] })
+	configs = expand(configs, len(g.MinRaters), func(c *ai.Config, i int) { c.MinRaters = g.MinRaters[i] })
+	configs = expand(configs, len(g.Shrinkage), func(c *ai.Config, i int) { c.Shrinkage = g.Shrinkage[i] })
+	configs = expand(configs, len(g.Solvers), func(c *ai.Config, i int) { c.Solver = g.Solvers[i] })
+	configs = expand(configs, len(g.Factors), func(c *ai.Config, i int) { c.Factors = g.Factors[i] })
+	configs = expand(configs, len(g.LearningRates), func(c *ai.Config, i int) { c.LearningRate = g.LearningRates[i] })
+	configs = expand(configs, len(g.Regularization), func(c *ai.Config, i int) { c.Regularization = g.Regularization[i] })
+	configs = expand(configs, len(g.Epochs), func(c *ai.Config, i int) { c.Epochs = g.Epochs[i] })
+	configs = expand(configs, len(g.HalfLives), func(c *ai.Config, i int) { c.HalfLife = g.HalfLives[i] })
+	configs = expand(configs, len(g.Windows), func(c *ai.Config, i int) { c.Window = g.Windows[i] })
+	configs = expand(configs, len(g.TimeBins), func(c *ai.Config, i int) { c.TimeBins = g.TimeBins[i] })
+	configs = expand(configs, len(g.BiPolar), func(c *ai.Config, i int) { c.BiPolar = g.BiPolar[i] })
+	configs = expand(configs, len(g.Clusters), func(c *ai.Config, i int) { c.Clusters = g.Clusters[i] })
+	configs = expand(configs, len(g.History), func(c *ai.Config, i int) { c.History = g.History[i] })
+	configs = expand(configs, len(g.Decay), func(c *ai.Config, i int) { c.Decay = g.Decay[i] })
+	return unique(configs)
+}
+
+// Sample draws n random configurations from the grid for a random search
+func (g Grid) Sample(n int, seed int64) []ai.Config {
+	rng := rand.New(rand.NewSource(seed))
+	pick := func(size int) int { return rng.Intn(size) }
+
+	configs := make([]ai.Config, n)
+	for i := range configs {
+		c := g.Base
+		if len(g.Algorithms) > 0 {
+			c.Algorithm = g.Algorithms[pick(len(g.Algorithms))]
+		}
+		if len(g.Metrics) > 0 {
+			c.Metric = g.Metrics[pick(len(g.Metrics))]
+		}
+		if len(g.Significance) > 0 {
+			c.Significance = g.Significance[pick(len(g.Significance))]
+		}
+		if len(g.Neighbours) > 0 {
+			c.Neighbours = g.Neighbours[pick(len(g.Neighbours))]
+		}
+		if len(g.MinSupport) > 0 {
+			c.MinSupport = g.MinSupport[pick(len(g.MinSupport))]
+		}
+		if len(g.MinRaters) > 0 {
+			c.MinRaters = g.MinRaters[pick(len(g.MinRaters))]
+		}
+		if len(g.Shrinkage) > 0 {
+			c.Shrinkage = g.Shrinkage[pick(len(g.Shrinkage))]
+		}
+		if len(g.Solvers) > 0 {
+			c.Solver = g.Solvers[pick(len(g.Solvers))]
+		}
+		if len(g.Factors) > 0 {
+			c.Factors = g.Factors[pick(len(g.Factors))]
+		}
+		if len(g.LearningRates) > 0 {
+			c.LearningRate = g.LearningRates[pick(len(g.LearningRates))]
+		}
+		if len(g.Regularization) > 0 {
+			c.Regularization = g.Regularization[pick(len(g.Regularization))]
+		}
+		if len(g.Epochs) > 0 {
+			c.Epochs = g.Epochs[pick(len(g.Epochs))]
+		}
+		if len(g.HalfLives) > 0 {
+			c.HalfLife = g.HalfLives[pick(len(g.HalfLives))]
+		}
+		if len(g.Windows) > 0 {
+			c.Window = g.Windows[pick(len(g.Windows))]
+		}
+		if len(g.TimeBins) > 0 {
+			c.TimeBins = g.TimeBins[pick(len(g.TimeBins))]
+		}
+		if len(g.BiPolar) > 0 {
+			c.BiPolar = g.BiPolar[pick(len(g.BiPolar))]
+		}
+		if len(g.Clusters) > 0 {
+			c.Clusters = g.Clusters[pick(len(g.Clusters))]
+		}
+		if len(g.History) > 0 {
+			c.History = g.History[pick(len(g.History))]
+		}
+		if len(g.Decay) > 0 {
+			c.Decay = g.Decay[pick(len(g.Decay))]
+		}
+		configs[i] = c
+	}
+	return unique(configs)
+}
+
+// Search cross validates every config and returns all trials along with the best one
+// by objective, ready to Save for serving. Configs that fail to validate or build are
+// kept as trials with their error and never picked as the best
+func Search(configs []ai.Config, splits []Split, opts Options, objective Metric) ([]Trial, Trial) {
+	var trials []Trial
+	var best Trial
+	bestScore := math.NaN()
+	for _, config := range configs {
+		if err := config.Validate(); err != nil {
+			trials = append(trials, Trial{Config: config, Err: err})
+			continue
+		}
+		c := relevant(config)
+		var buildErr error
+		model := Model{
+			Name: configName(c),
+			Train: func(train ai.Users) ai.Recommender {
+				rec, err := c.Build(train)
+				if err != nil {
+					buildErr = err
+					return unbuilt{}
+				}
+				return rec
+			},
+		}
+		trial := Trial{Config: c, Summary: CrossValidate(model, splits, opts)}
+		if buildErr != nil {
+			trials = append(trials, Trial{Config: c, Err: fmt.Errorf("could not build %s: %w", model.Name, buildErr)})
+			continue
+		}
+		trials = append(trials, trial)
+
+		score := trial.Summary.Mean[objective]
+		if math.IsNaN(score) {
+			continue
+		}
+		if math.IsNaN(bestScore) || (objective.LowerIsBetter() && score < bestScore) ||
+			(!objective.LowerIsBetter() && score > bestScore) {
+			best, bestScore = trial, score
+		}
+	}
+	return trials, best
+}
+
+func expand(configs []ai.Config, n int, set func(c *ai.Config, i int)) []ai.Config {
+	if n == 0 {
+		return configs
+	}
+	out := make([]ai.Config, 0, len(configs)*n)
+	for _, c := range configs {
+		for i := 0; i < n; i++ {
+			next := c
+			set(&next, i)
+			out = append(out, next)
+		}
+	}
+	return out
+}
+
+// unique drops configs that build the same recommender as an earlier one
+func unique(configs []ai.Config) []ai.Config {
+	seen := make(map[ai.Config]bool)
+	var out []ai.Config
+	for _, c := range configs {
+		key := relevant(c)
+		if !seen[key] {
+			seen[key] = true
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// relevant zeroes the parameters the config's algorithm does not use
+func relevant(c ai.Config) ai.Config {
+	switch c.Algorithm {
+	case ai.AlgorithmUser, ai.AlgorithmItem:
+		c.Solver, c.Factors, c.LearningRate, c.Regularization, c.Epochs, c.Seed, c.TimeBins = "", 0, 0, 0, 0, 0, 0
+		c.BiPolar, c.History, c.Decay = false, 0, 0
+		if c.Algorithm == ai.AlgorithmItem {
+			c.MinRaters, c.Clusters = 0, 0
+		}
+	case ai.AlgorithmFactorization:
+		c.Metric, c.Significance, c.Neighbours, c.MinSupport, c.MinRaters, c.Shrinkage = "", 0, 0, 0, 0, 0
+		c.HalfLife, c.Window, c.BiPolar, c.Clusters, c.History, c.Decay = 0, 0, false, 0, 0, 0
+	case ai.AlgorithmSlopeOne:
+		c = ai.Config{Algorithm: c.Algorithm, BiPolar: c.BiPolar}
+	case ai.AlgorithmMarkov:
+		c = ai.Config{Algorithm: c.Algorithm, History: c.History, Decay: c.Decay}
+	}
+	return c
+}
+
+func configName(c ai.Config) string {
+	body, _ := json.Marshal(c)
+	return string(body)
+}
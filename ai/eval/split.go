@@ -0,0 +1,117 @@
+package eval
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+
+	"golearn/ai"
+)
+
+// Split is a train and test partition of the same users' ratings
+type Split struct {
+	Train ai.Users
+	Test  ai.Users
+}
+
+// RandomSplit sends each rating to the test set with probability fraction
+func RandomSplit(users ai.Users, fraction float64, seed int64) Split {
+	rng := rand.New(rand.NewSource(seed))
+	return partition(users, func(_ int, user ai.User) []bool {
+		test := make([]bool, len(user.Ratings))
+		for i := range test {
+			test[i] = rng.Float64() < fraction
+		}
+		return test
+	})
+}
+
+// UserHoldout moves n randomly chosen ratings of every user with more than n ratings to the test set
+func UserHoldout(users ai.Users, n int, seed int64) Split {
+	rng := rand.New(rand.NewSource(seed))
+	return partition(users, func(_ int, user ai.User) []bool {
+		test := make([]bool, len(user.Ratings))
+		if len(test) <= n {
+			return test
+		}
+		for _, i := range rng.Perm(len(test))[:n] {
+			test[i] = true
+		}
+		return test
+	})
+}
+
+// TemporalSplit moves the most recent fraction of all ratings to the test set,
+// fraction must leave both sets something so is strictly between 0 and 1
+func TemporalSplit(users ai.Users, fraction float64) (Split, error) {
+	if fraction <= 0 || fraction >= 1 {
+		return Split{}, fmt.Errorf("temporal split fraction must be between 0 and 1, got %g", fraction)
+	}
+	var times []int64
+	for _, user := range users {
+		for _, r := range user.Ratings {
+			times = append(times, r.Timestamp)
+		}
+	}
+	if len(times) == 0 {
+		return Split{}, nil
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i] < times[j] })
+	cut := int(float64(len(times)) * (1 - fraction))
+	if cut >= len(times) {
+		cut = len(times) - 1
+	}
+	cutoff := times[cut]
+
+	return partition(users, func(_ int, user ai.User) []bool {
+		test := make([]bool, len(user.Ratings))
+		for i, r := range user.Ratings {
+			test[i] = r.Timestamp >= cutoff
+		}
+		return test
+	}), nil
+}
+
+// LeaveLastOut moves every user's most recent rating to the test set, users with a
+// single rating stay in training. Evaluate it with a Threshold of 0 so HitRate is the
+// share of users whose next movie was in their top K
+func LeaveLastOut(users ai.Users) Split {
+	return partition(users, func(_ int, user ai.User) []bool {
+		test := make([]bool, len(user.Ratings))
+		if len(test) < 2 {
+			return test
+		}
+		last := ai.Chronological(user)[len(test)-1]
+		for i, r := range user.Ratings {
+			if r == last {
+				test[i] = true
+				break
+			}
+		}
+		return test
+	})
+}
+
+// partition asks choose which of each user's ratings are test ratings, users without
+// any ratings on one side are left out of that side
+func partition(users ai.Users, choose func(i int, user ai.User) []bool) Split {
+	var split Split
+	for u, user := range users {
+		train, test := user, user
+		train.Ratings, test.Ratings = nil, nil
+		for i, isTest := range choose(u, user) {
+			if isTest {
+				test.Ratings = append(test.Ratings, user.Ratings[i])
+			} else {
+				train.Ratings = append(train.Ratings, user.Ratings[i])
+			}
+		}
+		if len(train.Ratings) > 0 {
+			split.Train = append(split.Train, train)
+		}
+		if len(test.Ratings) > 0 {
+			split.Test = append(split.Test, test)
+		}
+	}
+	return split
+}
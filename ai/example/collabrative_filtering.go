@@ -4,60 +4,80 @@ import (
 	"fmt"
 	"math"
 	"sort"
+
+	"golearn/ai"
 )
 
 type Ratings map[int]map[int]float64
 
-func cosineSimilarity(ratings Ratings, user1 int, user2 int) float64 {
-	sharedMovies := []int{}
-	for movie := range ratings[user1] {
-		if _, ok := ratings[user2][movie]; ok {
-			sharedMovies = append(sharedMovies, movie)
+// users turns the ratings into ai.Users so they can be indexed by an ai.Matrix
+func (r Ratings) users() ai.Users {
+	users := make(ai.Users, 0, len(r))
+	for userID, movies := range r {
+		user := ai.User{ID: userID}
+		for movieID, score := range movies {
+			user.Ratings = append(user.Ratings, ai.Rating{MovieID: movieID, Score: score})
 		}
+		users = append(users, user)
 	}
+	return users
+}
 
-	if len(sharedMovies) == 0 {
-		return 0.0
+func cosineSimilarity(user1, user2 ai.Vector) float64 {
+	dotProduct, sumSquares1, sumSquares2, shared := 0.0, 0.0, 0.0, 0
+	for i, j := 0, 0; i < len(user1.Index) && j < len(user2.Index); {
+		switch {
+		case user1.Index[i] < user2.Index[j]:
+			i++
+		case user1.Index[i] > user2.Index[j]:
+			j++
+		default:
+			rating1, rating2 := user1.Value[i], user2.Value[j]
+			dotProduct += rating1 * rating2
+			sumSquares1 += rating1 * rating1
+			sumSquares2 += rating2 * rating2
+			shared++
+			i++
+			j++
+		}
 	}
 
-	sum1, sum2, sumSquares := 0.0, 0.0, 0.0
-	for _, movie := range sharedMovies {
-		rating1 := ratings[user1][movie]
-		rating2 := ratings[user2][movie]
-		sum1 += rating1
-		sum2 += rating2
-		sumSquares += rating1 * rating1
-	}
-	mag1 := math.Sqrt(sumSquares)
-	mag2 := math.Sqrt(sum2 * sum2)
-
-	dotProduct := 0.0
-	for _, movie := range sharedMovies {
-		rating1 := ratings[user1][movie]
-		rating2 := ratings[user2][movie]
-		dotProduct += rating1 * rating2
+	if shared == 0 {
+		return 0.0
 	}
 
+	mag1 := math.Sqrt(sumSquares1)
+	mag2 := math.Sqrt(sumSquares2)
+
 	return dotProduct / (mag1 * mag2)
 }
 
-func getRecommendations(ratings Ratings, userID int, numRecs int) []int {
+func getRecommendations(ratings *ai.Matrix, userID int, numRecs int) []int {
+	user, ok := ratings.UserRow(userID)
+	if !ok {
+		return nil
+	}
+
 	similarities := map[int]float64{}
-	for otherID := range ratings {
+	for _, otherID := range ratings.UserIDs() {
 		if otherID == userID {
 			continue
 		}
-		similarities[otherID] = cosineSimilarity(ratings, userID, otherID)
+		other, _ := ratings.UserRow(otherID)
+		similarities[otherID] = cosineSimilarity(user, other)
 	}
 
 	recommendations := map[int]float64{}
-	for movieID := range ratings[1] {
+	for _, movieID := range ratings.MovieIDs() {
+		if _, ok := user.At(movieID); ok {
+			continue
+		}
+		raters, _ := ratings.MovieColumn(movieID)
 		ratingSum, simSum := 0.0, 0.0
-		for otherID, similarity := range similarities {
-			if rating, ok := ratings[otherID][movieID]; ok {
-				ratingSum += similarity * rating
-				simSum += similarity
-			}
+		for i, otherID := range raters.Index {
+			similarity := similarities[otherID]
+			ratingSum += similarity * raters.Value[i]
+			simSum += similarity
 		}
 		if simSum > 0 {
 			recommendations[movieID] = ratingSum / simSum
@@ -67,12 +87,19 @@ func getRecommendations(ratings Ratings, userID int, numRecs int) []int {
 	sortedRecs := make([]int, 0, len(recommendations))
 	for movieID := range recommendations {
 		sortedRecs = append(sortedRecs, movieID)
-		sort.Slice(sortedRecs, func(i, j int) bool {
-			return recommendations[sortedRecs[i]] > recommendations[sortedRecs[j]]
-		})
 	}
+	sort.Slice(sortedRecs, func(i, j int) bool {
+		a, b := sortedRecs[i], sortedRecs[j]
+		if recommendations[a] != recommendations[b] {
+			return recommendations[a] > recommendations[b]
+		}
+		return a < b
+	})
 
-	if numRecs < len(sortedRecs) {
+	switch {
+	case numRecs <= 0:
+		return nil
+	case numRecs < len(sortedRecs):
 		return sortedRecs[:numRecs]
 	}
 	return sortedRecs
@@ -89,7 +116,7 @@ func main() {
 	}
 
 	// Get recommendations for user 1
-	recs := getRecommendations(ratings, 1, 3)
+	recs := getRecommendations(ai.NewMatrix(ratings.users()), 1, 3)
 
 	// Print the recommended movie IDs
 	fmt.Println(recs)
@@ -0,0 +1,28 @@
+package example
+
+import (
+	"math"
+	"testing"
+
+	"golearn/ai"
+)
+
+func TestCosineSimilarityUsesBothMagnitudes(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b ai.Vector
+		want float64
+	}{
+		{"parallel", ai.Vector{Index: []int{1, 2}, Value: []float64{3, 4}}, ai.Vector{Index: []int{1, 2}, Value: []float64{6, 8}}, 1},
+		{"orthogonal", ai.Vector{Index: []int{1, 2}, Value: []float64{1, 0}}, ai.Vector{Index: []int{1, 2}, Value: []float64{0, 2}}, 0},
+		{"co-rated only", ai.Vector{Index: []int{1, 2}, Value: []float64{3, 4}}, ai.Vector{Index: []int{1, 2, 3}, Value: []float64{6, 8, 5}}, 1},
+		{"nothing shared", ai.Vector{Index: []int{1}, Value: []float64{5}}, ai.Vector{Index: []int{2}, Value: []float64{5}}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cosineSimilarity(tt.a, tt.b); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("cosineSimilarity = %g, want %g", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package example
+
+import (
+	"container/heap"
+	"sort"
+)
+
+// KDTree indexes movies by their features for exact nearest neighbour and radius
+// queries. The distance must never be smaller than the difference along any single
+// feature, which holds for Euclidean, Manhattan and every other Minkowski distance
+type KDTree struct {
+	dist   Distance
+	dims   int
+	movies []Movie
+	root   *kdNode
+}
+
+type kdNode struct {
+	index       int
+	axis        int
+	left, right *kdNode
+}
+
+// NewKDTree builds a balanced tree over movies, neighbours report their index in movies
+func NewKDTree(movies []Movie, dist Distance) *KDTree {
+	t := &KDTree{dist: dist, movies: append([]Movie(nil), movies...)}
+	t.Rebuild()
+	return t
+}
+
+// Len is the number of movies in the tree
+func (t *KDTree) Len() int {
+	return len(t.movies)
+}
+
+// Insert adds a movie below the existing nodes, it gets the next index. Many inserts
+// can leave the tree unbalanced, Rebuild fixes that
+func (t *KDTree) Insert(m Movie) {
+	t.movies = append(t.movies, m)
+	if t.dims == 0 {
+		t.dims = len(m.Features)
+	}
+	node := &kdNode{index: len(t.movies) - 1}
+	if t.root == nil {
+		t.root = node
+		return
+	}
+
+	depth := 0
+	for parent := t.root; ; depth++ {
+		next := &parent.right
+		if t.dims > 0 && m.Features[parent.axis] < t.movies[parent.index].Features[parent.axis] {
+			next = &parent.left
+		}
+		if *next == nil {
+			node.axis = t.axis(depth + 1)
+			*next = node
+			return
+		}
+		parent = *next
+	}
+}
+
+// Rebuild balances the tree by splitting every level on the median
+func (t *KDTree) Rebuild() {
+	t.dims = 0
+	if len(t.movies) > 0 {
+		t.dims = len(t.movies[0].Features)
+	}
+	indices := make([]int, len(t.movies))
+	for i := range indices {
+		indices[i] = i
+	}
+	t.root = t.build(indices, 0)
+}
+
+func (t *KDTree) build(indices []int, depth int) *kdNode {
+	if len(indices) == 0 {
+		return nil
+	}
+	axis := t.axis(depth)
+	if t.dims > 0 {
+		sort.Slice(indices, func(i, j int) bool {
+			a, b := t.movies[indices[i]].Features[axis], t.movies[indices[j]].Features[axis]
+			if a != b {
+				return a < b
+			}
+			return indices[i] < indices[j]
+		})
+	}
+	// values equal to the split can end up on either side, the search bounds allow for it
+	mid := len(indices) / 2
+	return &kdNode{
+		index: indices[mid],
+		axis:  axis,
+		left:  t.build(indices[:mid], depth+1),
+		right: t.build(indices[mid+1:], depth+1),
+	}
+}
+
+func (t *KDTree) axis(depth int) int {
+	if t.dims == 0 {
+		return 0
+	}
+	return depth % t.dims
+}
+
+// KNearest returns the same neighbours, in the same order, as KNearest over every movie
+func (t *KDTree) KNearest(query Movie, k int) []Neighbour {
+	if k <= 0 {
+		return nil
+	}
+	h := make(farthestFirst, 0, k+1)
+	t.nearest(t.root, query, k, &h)
+
+	neighbours := []Neighbour(h)
+	sort.Slice(neighbours, func(i, j int) bool {
+		return closer(neighbours[i], neighbours[j])
+	})
+	return neighbours
+}
+
+func (t *KDTree) nearest(node *kdNode, query Movie, k int, h *farthestFirst) {
+	if node == nil {
+		return
+	}
+	m := t.movies[node.index]
+	n := Neighbour{Movie: m, Index: node.index, Distance: t.dist(m.Features, query.Features)}
+	if h.Len() < k {
+		heap.Push(h, n)
+	} else if closer(n, (*h)[0]) {
+		(*h)[0] = n
+		heap.Fix(h, 0)
+	}
+
+	near, far, gap := t.sides(node, query)
+	t.nearest(near, query, k, h)
+	// a tie with the current worst can still win on index, so only prune when strictly farther
+	if h.Len() < k || gap <= (*h)[0].Distance {
+		t.nearest(far, query, k, h)
+	}
+}
+
+// Within returns every movie no farther than radius from query, nearest first
+func (t *KDTree) Within(query Movie, radius float64) []Neighbour {
+	var found []Neighbour
+	t.within(t.root, query, radius, &found)
+	sort.Slice(found, func(i, j int) bool {
+		return closer(found[i], found[j])
+	})
+	return found
+}
+
+func (t *KDTree) within(node *kdNode, query Movie, radius float64, found *[]Neighbour) {
+	if node == nil {
+		return
+	}
+	m := t.movies[node.index]
+	if d := t.dist(m.Features, query.Features); d <= radius {
+		*found = append(*found, Neighbour{Movie: m, Index: node.index, Distance: d})
+	}
+
+	near, far, gap := t.sides(node, query)
+	t.within(near, query, radius, found)
+	if gap <= radius {
+		t.within(far, query, radius, found)
+	}
+}
+
+// sides orders the node's children by which side of the split query falls on and
+// returns how far query is from the split
+func (t *KDTree) sides(node *kdNode, query Movie) (*kdNode, *kdNode, float64) {
+	if t.dims == 0 {
+		return node.left, node.right, 0
+	}
+	diff := query.Features[node.axis] - t.movies[node.index].Features[node.axis]
+	if diff < 0 {
+		return node.left, node.right, -diff
+	}
+	return node.right, node.left, diff
+}
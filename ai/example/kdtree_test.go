@@ -0,0 +1,69 @@
+package example
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// randomMovies has features on a small integer grid so many distances tie
+func randomMovies(rng *rand.Rand, n, dims int) []Movie {
+	movies := make([]Movie, n)
+	for i := range movies {
+		features := make([]float64, dims)
+		for d := range features {
+			features[d] = float64(rng.Intn(5))
+		}
+		movies[i] = Movie{Name: fmt.Sprintf("movie %d", i), Features: features}
+	}
+	return movies
+}
+
+func TestKDTreeMatchesBruteForce(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	movies := randomMovies(rng, 300, 3)
+	queries := randomMovies(rng, 40, 3)
+	distances := map[string]Distance{"euclidean": Euclidean, "manhattan": Manhattan}
+
+	for name, dist := range distances {
+		tree := NewKDTree(movies, dist)
+		for _, q := range queries {
+			for _, k := range []int{0, 1, 5, 37, len(movies) + 10} {
+				got, want := tree.KNearest(q, k), KNearest(movies, q, k, dist)
+				if !reflect.DeepEqual(got, want) {
+					t.Fatalf("%s: KNearest(%v, %d) = %v, brute force gives %v", name, q.Features, k, got, want)
+				}
+			}
+
+			all := KNearest(movies, q, len(movies), dist)
+			for _, radius := range []float64{0, 1, 2.5} {
+				var want []Neighbour
+				for _, n := range all {
+					if n.Distance <= radius {
+						want = append(want, n)
+					}
+				}
+				if got := tree.Within(q, radius); !reflect.DeepEqual(got, want) {
+					t.Fatalf("%s: Within(%v, %g) = %v, brute force gives %v", name, q.Features, radius, got, want)
+				}
+			}
+		}
+	}
+}
+
+func TestKDTreeInsertMatchesBruteForce(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	movies := randomMovies(rng, 200, 4)
+	tree := NewKDTree(movies[:50], Euclidean)
+	for _, m := range movies[50:] {
+		tree.Insert(m)
+	}
+
+	for _, q := range randomMovies(rng, 40, 4) {
+		got, want := tree.KNearest(q, 10), KNearest(movies, q, 10, Euclidean)
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("KNearest(%v, 10) after inserts = %v, brute force gives %v", q.Features, got, want)
+		}
+	}
+}
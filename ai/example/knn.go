@@ -0,0 +1,194 @@
+package example
+
+import (
+	"container/heap"
+	"math"
+	"sort"
+)
+
+// Distance measures how far apart two feature vectors of the same length are
+type Distance func(a, b []float64) float64
+
+// Neighbour is a movie found by a nearest neighbour search, Index is its position in
+// the searched slice
+type Neighbour struct {
+	Movie    Movie
+	Index    int
+	Distance float64
+}
+
+// Euclidean is the straight line distance
+func Euclidean(a, b []float64) float64 {
+	sum := 0.0
+	for i := range a {
+		sum += (a[i] - b[i]) * (a[i] - b[i])
+	}
+	return math.Sqrt(sum)
+}
+
+// Manhattan is the sum of the absolute differences
+func Manhattan(a, b []float64) float64 {
+	sum := 0.0
+	for i := range a {
+		sum += math.Abs(a[i] - b[i])
+	}
+	return sum
+}
+
+// CosineDistance is one minus the cosine of the angle between a and b, a zero vector
+// is as far as possible from everything
+func CosineDistance(a, b []float64) float64 {
+	dot, sumA, sumB := 0.0, 0.0, 0.0
+	for i := range a {
+		dot += a[i] * b[i]
+		sumA += a[i] * a[i]
+		sumB += b[i] * b[i]
+	}
+	if sumA == 0 || sumB == 0 {
+		return 1
+	}
+	return 1 - dot/(math.Sqrt(sumA)*math.Sqrt(sumB))
+}
+
+// JaccardDistance treats every positive feature as a set member
+func JaccardDistance(a, b []float64) float64 {
+	both, either := 0, 0
+	for i := range a {
+		if a[i] > 0 || b[i] > 0 {
+			either++
+			if a[i] > 0 && b[i] > 0 {
+				both++
+			}
+		}
+	}
+	if either == 0 {
+		return 0
+	}
+	return 1 - float64(both)/float64(either)
+}
+
+// WeightedEuclidean scales each squared difference by its feature weight
+func WeightedEuclidean(weights []float64) Distance {
+	return func(a, b []float64) float64 {
+		sum := 0.0
+		for i := range a {
+			sum += weights[i] * (a[i] - b[i]) * (a[i] - b[i])
+		}
+		return math.Sqrt(sum)
+	}
+}
+
+// KNearest returns exactly min(k, len(movies)) movies closest to query, nearest first
+// with equal distances kept in their original order
+func KNearest(movies []Movie, query Movie, k int, dist Distance) []Neighbour {
+	if k <= 0 {
+		return nil
+	}
+	h := make(farthestFirst, 0, k+1)
+	for i := range movies {
+		n := Neighbour{Movie: movies[i], Index: i, Distance: dist(movies[i].Features, query.Features)}
+		if len(h) < k {
+			heap.Push(&h, n)
+		} else if closer(n, h[0]) {
+			h[0] = n
+			heap.Fix(&h, 0)
+		}
+	}
+
+	neighbours := []Neighbour(h)
+	sort.Slice(neighbours, func(i, j int) bool {
+		return closer(neighbours[i], neighbours[j])
+	})
+	return neighbours
+}
+
+func closer(a, b Neighbour) bool {
+	if a.Distance != b.Distance {
+		return a.Distance < b.Distance
+	}
+	return a.Index < b.Index
+}
+
+// farthestFirst is a max heap, the root is the neighbour to drop when a closer one turns up
+type farthestFirst []Neighbour
+
+func (h farthestFirst) Len() int            { return len(h) }
+func (h farthestFirst) Less(i, j int) bool  { return closer(h[j], h[i]) }
+func (h farthestFirst) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *farthestFirst) Push(x interface{}) { *h = append(*h, x.(Neighbour)) }
+func (h *farthestFirst) Pop() interface{} {
+	old := *h
+	n := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return n
+}
+
+// Normalizer rescales every feature as (value - Offset) / Scale
+type Normalizer struct {
+	Offset []float64
+	Scale  []float64
+}
+
+// MinMax maps every feature onto 0..1 across movies
+func MinMax(movies []Movie) Normalizer {
+	if len(movies) == 0 {
+		return Normalizer{}
+	}
+	size := len(movies[0].Features)
+	n := Normalizer{Offset: make([]float64, size), Scale: make([]float64, size)}
+	for f := 0; f < size; f++ {
+		lo, hi := math.Inf(1), math.Inf(-1)
+		for _, m := range movies {
+			lo = math.Min(lo, m.Features[f])
+			hi = math.Max(hi, m.Features[f])
+		}
+		n.Offset[f], n.Scale[f] = lo, hi-lo
+	}
+	return n
+}
+
+// ZScore centres every feature on its mean and divides by its standard deviation
+func ZScore(movies []Movie) Normalizer {
+	if len(movies) == 0 {
+		return Normalizer{}
+	}
+	size := len(movies[0].Features)
+	n := Normalizer{Offset: make([]float64, size), Scale: make([]float64, size)}
+	count := float64(len(movies))
+	for f := 0; f < size; f++ {
+		mean := 0.0
+		for _, m := range movies {
+			mean += m.Features[f]
+		}
+		mean /= count
+		variance := 0.0
+		for _, m := range movies {
+			variance += (m.Features[f] - mean) * (m.Features[f] - mean)
+		}
+		n.Offset[f], n.Scale[f] = mean, math.Sqrt(variance/count)
+	}
+	return n
+}
+
+// Apply returns a copy of m with normalised features, constant features become 0
+func (n Normalizer) Apply(m Movie) Movie {
+	features := make([]float64, len(m.Features))
+	for f := range features {
+		switch {
+		case f >= len(n.Scale):
+			features[f] = m.Features[f]
+		case n.Scale[f] != 0:
+			features[f] = (m.Features[f] - n.Offset[f]) / n.Scale[f]
+		}
+	}
+	return Movie{Name: m.Name, Features: features}
+}
+
+// ApplyAll normalises every movie
+func (n Normalizer) ApplyAll(movies []Movie) []Movie {
+	out := make([]Movie, len(movies))
+	for i := range movies {
+		out[i] = n.Apply(movies[i])
+	}
+	return out
+}
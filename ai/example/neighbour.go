@@ -1,10 +1,6 @@
 package example
 
-import (
-	"fmt"
-	"math"
-	"sort"
-)
+import "fmt"
 
 // Movie Define a struct to represent a movie
 type Movie struct {
@@ -25,55 +21,39 @@ type Features struct {
 	Fantasy     float64
 }
 
-// Define a function to calculate the Euclidean distance between two movies
-func euclideanDistance(a, b Movie) float64 {
-	sum := 0.0
-	for i := range a.Features {
-		sum += math.Pow(a.Features[i]-b.Features[i], 2)
-	}
-	return math.Sqrt(sum)
-}
-
 // Define a function to find the k nearest neighbors for a given movie
 func findKNearestNeighbors(movies []Movie, m Movie, k int) []Movie {
-	distances := make(map[float64][]Movie)
-	for i := range movies {
-		distance := euclideanDistance(movies[i], m)
-		distances[distance] = append(distances[distance], movies[i])
-	}
-	var keys []float64
-	for k := range distances {
-		keys = append(keys, k)
-	}
-	sort.Float64s(keys)
 	var neighbors []Movie
-	for _, key := range keys {
-		if len(neighbors) >= k {
-			break
-		}
-		neighbors = append(neighbors, distances[key]...)
+	for _, n := range KNearest(movies, m, k, Euclidean) {
+		neighbors = append(neighbors, n.Movie)
 	}
 	return neighbors
 }
 
-// Define a function to recommend movies based on a given movie
+// Define a function to recommend movies based on a given movie, nearest first
 func recommendMovies(movies []Movie, m Movie, k int) []Movie {
-	neighbors := findKNearestNeighbors(movies, m, k)
-	recommendations := make(map[string]Movie)
-	for i := range neighbors {
-		for j := range neighbors[i].Features {
-			if neighbors[i].Features[j] > 0 && m.Features[j] == 0 {
-				recommendations[neighbors[i].Name] = neighbors[i]
-			}
-		}
-	}
 	var recommendedMovies []Movie
-	for _, r := range recommendations {
-		recommendedMovies = append(recommendedMovies, r)
+	for _, n := range recommendNeighbours(movies, m, k) {
+		recommendedMovies = append(recommendedMovies, n.Movie)
 	}
 	return recommendedMovies
 }
 
+// recommendNeighbours is recommendMovies keeping each movie's index in movies, so
+// movies with the same name can be told apart
+func recommendNeighbours(movies []Movie, m Movie, k int) []Neighbour {
+	var recommended []Neighbour
+	for _, n := range KNearest(movies, m, k, Euclidean) {
+		for j := range n.Movie.Features {
+			if n.Movie.Features[j] > 0 && m.Features[j] == 0 {
+				recommended = append(recommended, n)
+				break
+			}
+		}
+	}
+	return recommended
+}
+
 func Learn() {
 	// Define some sample movies
 	movies := []Movie{
@@ -0,0 +1,87 @@
+package example
+
+import "golearn/ai"
+
+// UserBased adapts getRecommendations to ai.Recommender, scores are left at zero
+type UserBased struct {
+	ratings *ai.Matrix
+}
+
+// ContentBased adapts recommendNeighbours to ai.Recommender by looking for neighbours of
+// each user's highest rated movie among the movies they have not rated
+type ContentBased struct {
+	order  []int
+	movies map[int]Movie
+	users  *ai.Matrix
+}
+
+func NewUserBased(users ai.Users) UserBased {
+	return UserBased{ratings: ai.NewMatrix(users)}
+}
+
+func (ub UserBased) TopN(userID, n int) []ai.Rating {
+	var recs []ai.Rating
+	for _, movieID := range getRecommendations(ub.ratings, userID, n) {
+		recs = append(recs, ai.Rating{MovieID: movieID})
+	}
+	return recs
+}
+
+func NewContentBased(users ai.Users, catalogue ai.Movies) ContentBased {
+	cb := ContentBased{
+		movies: make(map[int]Movie, len(catalogue)),
+		users:  ai.NewMatrix(users),
+	}
+	for _, m := range catalogue {
+		cb.order = append(cb.order, m.ID)
+		cb.movies[m.ID] = genreMovie(m)
+	}
+	return cb
+}
+
+func (cb ContentBased) TopN(userID, n int) []ai.Rating {
+	rated, ok := cb.users.UserRow(userID)
+	if !ok {
+		return nil
+	}
+	// movies missing from the catalogue have no features to compare
+	best := -1
+	for i, id := range rated.Index {
+		if _, ok := cb.movies[id]; ok && (best < 0 || rated.Value[i] > rated.Value[best]) {
+			best = i
+		}
+	}
+	if best < 0 {
+		return nil
+	}
+
+	// ids[i] is the ID of candidates[i], titles are not unique in u.item
+	var candidates []Movie
+	var ids []int
+	for _, id := range cb.order {
+		if _, ok := rated.At(id); !ok {
+			candidates = append(candidates, cb.movies[id])
+			ids = append(ids, id)
+		}
+	}
+
+	var recs []ai.Rating
+	for _, m := range recommendNeighbours(candidates, cb.movies[rated.Index[best]], n) {
+		if len(recs) == n {
+			break
+		}
+		recs = append(recs, ai.Rating{MovieID: ids[m.Index]})
+	}
+	return recs
+}
+
+// genreMovie uses the u.item genre flags as the movie's features
+func genreMovie(m ai.Movie) Movie {
+	features := make([]float64, len(m.Genres))
+	for i, ok := range m.Genres {
+		if ok {
+			features[i] = 1
+		}
+	}
+	return Movie{Name: m.Name, Features: features}
+}
@@ -0,0 +1,199 @@
+package ai
+
+import (
+	"math"
+	"sort"
+)
+
+// explainLimit is how many contributions an explanation keeps
+const explainLimit = 5
+
+// Explainer says why it scored a movie the way it did
+type Explainer interface {
+	Explain(userID, movieID int) Explanation
+}
+
+// Contribution is one user or movie that moved a prediction. The contributions add
+// up to the score for movie neighbours and to the score less the user's mean for
+// user neighbours
+type Contribution struct {
+	ID           int
+	Similarity   float64
+	Score        float64
+	Contribution float64
+}
+
+// Step is one node on a path through the rating graph, Kind is "user" or "movie"
+type Step struct {
+	Kind string
+	ID   int
+}
+
+// Explanation holds the reasons behind one recommendation, strongest first
+type Explanation struct {
+	Neighbours []Contribution // similar users who rated the movie
+	Items      []Contribution // the user's own ratings of similar movies
+	Genres     []string       // genres of the movie found in movies the user liked
+	Path       []Step         // the user, the neighbour or rated movie that raised the score most, the movie
+}
+
+// Recommendation is a recommended movie with the reasons for it
+type Recommendation struct {
+	Rating
+	Explanation Explanation
+}
+
+// Recommend returns rec's top n for the user, explained when rec is an Explainer.
+// Genres are filled from movies for every recommender
+func Recommend(rec Recommender, user User, movies Movies, n int) []Recommendation {
+	genres := make(map[int][GenreCount]bool, len(movies))
+	for _, m := range movies {
+		genres[m.ID] = m.Genres
+	}
+	liked := likedGenres(user, genres)
+
+	explainer, explains := rec.(Explainer)
+	var recs []Recommendation
+	for _, r := range rec.TopN(user.ID, n) {
+		var e Explanation
+		if explains {
+			e = explainer.Explain(user.ID, r.MovieID)
+		}
+		for g, ok := range genres[r.MovieID] {
+			if ok && liked[g] {
+				e.Genres = append(e.Genres, GenreNames[g])
+			}
+		}
+		recs = append(recs, Recommendation{Rating: r, Explanation: e})
+	}
+	return recs
+}
+
+// likedGenres flags every genre of a movie the user rated at or above their mean
+func likedGenres(user User, genres map[int][GenreCount]bool) [GenreCount]bool {
+	var liked [GenreCount]bool
+	if len(user.Ratings) == 0 {
+		return liked
+	}
+	mean := 0.0
+	for _, r := range user.Ratings {
+		mean += r.Score
+	}
+	mean /= float64(len(user.Ratings))
+	for _, r := range user.Ratings {
+		if r.Score < mean {
+			continue
+		}
+		for g, ok := range genres[r.MovieID] {
+			liked[g] = liked[g] || ok
+		}
+	}
+	return liked
+}
+
+// Explain lists the neighbours who rated movieID by how far they pushed the user's
+// prediction from the user's mean
+func (ub *UserBased) Explain(userID, movieID int) Explanation {
+	ub.mu.RLock()
+	defer ub.mu.RUnlock()
+	var list []Contribution
+	weights := 0.0
+	for _, n := range ub.neighboursOf(userID) {
+		score, at, ok := ub.Matrix.ratingAt(n.ID, movieID)
+		if w := ub.Options.Time.Weight(at, ub.Matrix.Latest()); ok && w > 0 {
+			list = append(list, Contribution{
+				ID:           n.ID,
+				Similarity:   n.Similarity,
+				Score:        score,
+				Contribution: w * n.Similarity * (score - ub.Matrix.UserMean(n.ID)),
+			})
+			weights += w * math.Abs(n.Similarity)
+		}
+	}
+	list = strongest(list, weights)
+
+	e := Explanation{Neighbours: list}
+	if via, ok := strongestPositive(list); ok {
+		e.Path = []Step{{"user", userID}, {"user", via}, {"movie", movieID}}
+	}
+	return e
+}
+
+// Explain lists the user's ratings of movieID's neighbours by how far they pushed the
+// prediction from the user's mean
+func (ib *ItemBased) Explain(userID, movieID int) Explanation {
+	ib.mu.RLock()
+	defer ib.mu.RUnlock()
+	var list []Contribution
+	mean := ib.matrix.UserMean(userID)
+	weights := ib.Shrinkage
+	for _, n := range ib.Neighbours[movieID] {
+		score, at, ok := ib.matrix.ratingAt(userID, n.ID)
+		if w := ib.Time.Weight(at, ib.matrix.Latest()); ok && w > 0 {
+			list = append(list, Contribution{ID: n.ID, Similarity: n.Similarity, Score: score, Contribution: w * n.Similarity * (score - mean)})
+			weights += w * math.Abs(n.Similarity)
+		}
+	}
+	return itemExplanation(userID, movieID, strongest(list, weights))
+}
+
+// Explain lists the user's ratings of movies with genres like movieID's
+func (cb *ContentBased) Explain(userID, movieID int) Explanation {
+	cb.mu.RLock()
+	defer cb.mu.RUnlock()
+	features, ok := cb.Features[movieID]
+	if !ok {
+		return Explanation{}
+	}
+	row, _ := cb.matrix.UserRow(userID)
+	var list []Contribution
+	weights := 0.0
+	for i, ratedID := range row.Index {
+		other, ok := cb.Features[ratedID]
+		if !ok || ratedID == movieID {
+			continue
+		}
+		if sim := ratio(dot(features, other), norm(features, 0)*norm(other, 0)); sim > 0 {
+			list = append(list, Contribution{ID: ratedID, Similarity: sim, Score: row.Value[i], Contribution: sim * row.Value[i]})
+			weights += sim
+		}
+	}
+	return itemExplanation(userID, movieID, strongest(list, weights))
+}
+
+func itemExplanation(userID, movieID int, list []Contribution) Explanation {
+	e := Explanation{Items: list}
+	if via, ok := strongestPositive(list); ok {
+		e.Path = []Step{{"user", userID}, {"movie", via}, {"movie", movieID}}
+	}
+	return e
+}
+
+// strongestPositive is the first contribution that raised the prediction
+func strongestPositive(list []Contribution) (int, bool) {
+	for _, c := range list {
+		if c.Contribution > 0 {
+			return c.ID, true
+		}
+	}
+	return 0, false
+}
+
+// strongest divides every contribution by the summed weights, as the prediction
+// does, and keeps the explainLimit largest by size
+func strongest(list []Contribution, weights float64) []Contribution {
+	for i := range list {
+		list[i].Contribution = ratio(list[i].Contribution, weights)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		a, b := math.Abs(list[i].Contribution), math.Abs(list[j].Contribution)
+		if a != b {
+			return a > b
+		}
+		return list[i].ID < list[j].ID
+	})
+	if len(list) > explainLimit {
+		return list[:explainLimit]
+	}
+	return list
+}
@@ -0,0 +1,415 @@
+package ai
+
+import (
+	"math"
+	"math/rand"
+	"sync"
+)
+
+// Solver picks how a Factorization is trained
+type Solver int
+
+const (
+	// SGD trains biased SVD with stochastic gradient descent
+	SGD Solver = iota
+	// ALS alternates exact least squares solves for users and movies
+	ALS
+)
+
+// FactorizationConfig holds the training parameters of a Factorization
+type FactorizationConfig struct {
+	Solver         Solver
+	Factors        int
+	LearningRate   float64
+	Regularization float64
+	Epochs         int
+	Seed           int64
+	TimeBins       int // item bias bins over the rating period, fitted after the factors
+}
+
+// DefaultFactorization is a reasonable starting point for MovieLens 100k
+var DefaultFactorization = FactorizationConfig{
+	Solver:         SGD,
+	Factors:        20,
+	LearningRate:   0.005,
+	Regularization: 0.02,
+	Epochs:         20,
+	Seed:           1,
+}
+
+// Factorization predicts mean + user bias + movie bias + user factors . movie factors.
+// With TimeBins it adds the movie's bias in the time bin of the rating
+type Factorization struct {
+	Config      FactorizationConfig
+	GlobalMean  float64
+	Min, Max    float64
+	UserBias    map[int]float64
+	ItemBias    map[int]float64
+	UserFactors map[int][]float64
+	ItemFactors map[int][]float64
+
+	MinTime, MaxTime int64
+	ItemBinBias      map[int][]float64
+
+	matrix *Matrix
+	mu     sync.RWMutex // written by AddRating, FoldIn and RemoveRating, read by everything else
+}
+
+// NewFactorization trains a latent factor model on the users' ratings
+func NewFactorization(users Users, config FactorizationConfig) *Factorization {
+	f := &Factorization{
+		Config:      config,
+		UserBias:    make(map[int]float64),
+		ItemBias:    make(map[int]float64),
+		UserFactors: make(map[int][]float64),
+		ItemFactors: make(map[int][]float64),
+		matrix:      NewMatrix(users),
+	}
+
+	data := f.matrix.entries()
+	if len(data) == 0 {
+		return f
+	}
+	f.Min, f.Max = data[0].score, data[0].score
+	for _, e := range data {
+		f.GlobalMean += e.score
+		f.Min = math.Min(f.Min, e.score)
+		f.Max = math.Max(f.Max, e.score)
+	}
+	f.GlobalMean /= float64(len(data))
+	if config.TimeBins > 0 {
+		f.initTime(data)
+	}
+
+	rng := rand.New(rand.NewSource(config.Seed))
+	for _, e := range data {
+		if _, ok := f.UserFactors[e.userID]; !ok {
+			f.UserFactors[e.userID] = randomFactors(rng, config.Factors)
+		}
+		if _, ok := f.ItemFactors[e.movieID]; !ok {
+			f.ItemFactors[e.movieID] = randomFactors(rng, config.Factors)
+		}
+	}
+
+	switch config.Solver {
+	case ALS:
+		f.trainALS()
+	default:
+		f.trainSGD(data, rng)
+	}
+	if config.TimeBins > 0 {
+		f.fitBins(data)
+	}
+	return f
+}
+
+// Predict scores movieID for userID as of the user's newest rating, ok is false when
+// either was not seen in training
+func (f *Factorization) Predict(userID, movieID int) (float64, bool) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.predict(userID, movieID)
+}
+
+func (f *Factorization) predict(userID, movieID int) (float64, bool) {
+	if f.Config.TimeBins == 0 {
+		return f.predictAt(userID, movieID, 0)
+	}
+	latest := f.MaxTime
+	if times := f.matrix.UserTimes(userID); len(times) > 0 {
+		latest = times[0]
+		for _, t := range times {
+			if t > latest {
+				latest = t
+			}
+		}
+	}
+	return f.predictAt(userID, movieID, latest)
+}
+
+// PredictAt is Predict for a rating made at a Unix time, which only matters with TimeBins
+func (f *Factorization) PredictAt(userID, movieID int, timestamp int64) (float64, bool) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.predictAt(userID, movieID, timestamp)
+}
+
+func (f *Factorization) predictAt(userID, movieID int, timestamp int64) (float64, bool) {
+	score := f.GlobalMean + f.UserBias[userID] + f.ItemBias[movieID] + f.timeBias(movieID, timestamp)
+	p, userOK := f.UserFactors[userID]
+	q, itemOK := f.ItemFactors[movieID]
+	if userOK && itemOK {
+		score += dot(p, q)
+	}
+	return f.clip(score), userOK && itemOK
+}
+
+// Support is the fewer of the ratings the user's and movieID's factors were trained on
+func (f *Factorization) Support(userID, movieID int) int {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	row, _ := f.matrix.UserRow(userID)
+	col, _ := f.matrix.MovieColumn(movieID)
+	if len(row.Index) < len(col.Index) {
+		return len(row.Index)
+	}
+	return len(col.Index)
+}
+
+// TopN predicts every movie the user has not rated and returns the n best
+func (f *Factorization) TopN(userID, n int) []Rating {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	if _, ok := f.UserFactors[userID]; !ok {
+		return coldStart(f.matrix, userID, n)
+	}
+	rated, _ := f.matrix.UserRow(userID)
+	recs := make([]Rating, 0, len(f.ItemFactors))
+	for movieID := range f.ItemFactors {
+		if _, ok := rated.At(movieID); ok {
+			continue
+		}
+		score, _ := f.predict(userID, movieID)
+		recs = append(recs, Rating{MovieID: movieID, Score: score})
+	}
+	return topRatings(recs, n)
+}
+
+func (f *Factorization) clip(score float64) float64 {
+	if f.Max > f.Min {
+		return math.Max(f.Min, math.Min(f.Max, score))
+	}
+	return score
+}
+
+func (f *Factorization) trainSGD(data []entry, rng *rand.Rand) {
+	lr, reg := f.Config.LearningRate, f.Config.Regularization
+	for epoch := 0; epoch < f.Config.Epochs; epoch++ {
+		rng.Shuffle(len(data), func(i, j int) {
+			data[i], data[j] = data[j], data[i]
+		})
+		for _, e := range data {
+			p, q := f.UserFactors[e.userID], f.ItemFactors[e.movieID]
+			err := e.score - (f.GlobalMean + f.UserBias[e.userID] + f.ItemBias[e.movieID] +
+				f.timeBias(e.movieID, e.timestamp) + dot(p, q))
+
+			f.UserBias[e.userID] += lr * (err - reg*f.UserBias[e.userID])
+			f.ItemBias[e.movieID] += lr * (err - reg*f.ItemBias[e.movieID])
+			for k := range p {
+				pk, qk := p[k], q[k]
+				p[k] += lr * (err*qk - reg*pk)
+				q[k] += lr * (err*pk - reg*qk)
+			}
+		}
+	}
+}
+
+// trainALS solves each user's bias and factors with the movies held fixed, then the
+// other way round, by treating the bias as a factor paired with a constant 1
+func (f *Factorization) trainALS() {
+	for epoch := 0; epoch < f.Config.Epochs; epoch++ {
+		for _, userID := range f.matrix.UserIDs() {
+			f.solveUser(userID)
+		}
+		for _, movieID := range f.matrix.MovieIDs() {
+			f.solveItem(movieID)
+		}
+	}
+}
+
+// solveUser fits the user's bias and factors to their ratings with the movies held fixed
+func (f *Factorization) solveUser(userID int) {
+	row, _ := f.matrix.UserRow(userID)
+	features := make([][]float64, len(row.Index))
+	targets := make([]float64, len(row.Index))
+	times := f.matrix.UserTimes(userID)
+	for i, movieID := range row.Index {
+		features[i] = f.ItemFactors[movieID]
+		targets[i] = row.Value[i] - f.GlobalMean - f.ItemBias[movieID] - f.timeBias(movieID, times[i])
+	}
+	f.UserBias[userID] = f.leastSquares(features, targets, f.UserFactors[userID])
+}
+
+// solveItem is solveUser for a movie with the users held fixed
+func (f *Factorization) solveItem(movieID int) {
+	col, _ := f.matrix.MovieColumn(movieID)
+	features := make([][]float64, len(col.Index))
+	targets := make([]float64, len(col.Index))
+	for i, userID := range col.Index {
+		_, at, _ := f.matrix.ratingAt(userID, movieID)
+		features[i] = f.UserFactors[userID]
+		targets[i] = col.Value[i] - f.GlobalMean - f.UserBias[userID] - f.timeBias(movieID, at)
+	}
+	f.ItemBias[movieID] = f.leastSquares(features, targets, f.ItemFactors[movieID])
+}
+
+// refitUser retrains only the user with the model's own solver, SGD models overfit a
+// few ratings when solved exactly
+func (f *Factorization) refitUser(userID int) {
+	if f.Config.Solver == ALS {
+		f.solveUser(userID)
+		return
+	}
+	row, _ := f.matrix.UserRow(userID)
+	times := f.matrix.UserTimes(userID)
+	p := f.UserFactors[userID]
+	lr, reg := f.Config.LearningRate, f.Config.Regularization
+	for epoch := 0; epoch < f.Config.Epochs; epoch++ {
+		for i, movieID := range row.Index {
+			q := f.ItemFactors[movieID]
+			err := row.Value[i] - (f.GlobalMean + f.UserBias[userID] + f.ItemBias[movieID] +
+				f.timeBias(movieID, times[i]) + dot(p, q))
+			f.UserBias[userID] += lr * (err - reg*f.UserBias[userID])
+			for k := range p {
+				p[k] += lr * (err*q[k] - reg*p[k])
+			}
+		}
+	}
+}
+
+// refitItem is refitUser for a movie
+func (f *Factorization) refitItem(movieID int) {
+	if f.Config.Solver == ALS {
+		f.solveItem(movieID)
+		return
+	}
+	col, _ := f.matrix.MovieColumn(movieID)
+	q := f.ItemFactors[movieID]
+	lr, reg := f.Config.LearningRate, f.Config.Regularization
+	for epoch := 0; epoch < f.Config.Epochs; epoch++ {
+		for i, userID := range col.Index {
+			p := f.UserFactors[userID]
+			_, at, _ := f.matrix.ratingAt(userID, movieID)
+			err := col.Value[i] - (f.GlobalMean + f.UserBias[userID] + f.ItemBias[movieID] +
+				f.timeBias(movieID, at) + dot(p, q))
+			f.ItemBias[movieID] += lr * (err - reg*f.ItemBias[movieID])
+			for k := range q {
+				q[k] += lr * (err*p[k] - reg*q[k])
+			}
+		}
+	}
+}
+
+// AddRating refits the user with the movies held fixed, and a movie new to the model
+// with the users held fixed, instead of retraining
+func (f *Factorization) AddRating(userID int, r Rating) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.foldIn(userID, []Rating{r})
+}
+
+// FoldIn adds ratings for a user, who may be new, and refits only that user and any
+// movies new to the model
+func (f *Factorization) FoldIn(userID int, ratings []Rating) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.foldIn(userID, ratings)
+}
+
+func (f *Factorization) foldIn(userID int, ratings []Rating) {
+	for _, r := range ratings {
+		f.matrix.AddRating(userID, r)
+	}
+	if _, ok := f.UserFactors[userID]; !ok {
+		f.UserFactors[userID] = make([]float64, f.Config.Factors)
+	}
+	for _, r := range ratings {
+		if _, ok := f.ItemFactors[r.MovieID]; !ok {
+			f.ItemFactors[r.MovieID] = make([]float64, f.Config.Factors)
+			f.refitItem(r.MovieID)
+		}
+	}
+	f.refitUser(userID)
+}
+
+// RemoveRating refits the user without the rating, a user left with no ratings is
+// forgotten
+func (f *Factorization) RemoveRating(userID, movieID int) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.matrix.RemoveRating(userID, movieID) {
+		return false
+	}
+	if f.matrix.HasUser(userID) {
+		f.refitUser(userID)
+	} else {
+		delete(f.UserBias, userID)
+		delete(f.UserFactors, userID)
+	}
+	return true
+}
+
+// leastSquares fits targets ~ bias + features . out with ridge regularisation,
+// writing the factors into out and returning the bias
+func (f *Factorization) leastSquares(features [][]float64, targets []float64, out []float64) float64 {
+	size := len(out) + 1
+	a := make([][]float64, size)
+	for i := range a {
+		a[i] = make([]float64, size)
+		a[i][i] = f.Config.Regularization * float64(len(targets))
+	}
+	b := make([]float64, size)
+
+	x := make([]float64, size)
+	for n, row := range features {
+		x[0] = 1
+		copy(x[1:], row)
+		for i := range x {
+			b[i] += x[i] * targets[n]
+			for j := range x {
+				a[i][j] += x[i] * x[j]
+			}
+		}
+	}
+
+	w := solve(a, b)
+	if w == nil {
+		return 0.0
+	}
+	copy(out, w[1:])
+	return w[0]
+}
+
+// solve returns x with a x = b using Gaussian elimination, or nil when a is singular
+func solve(a [][]float64, b []float64) []float64 {
+	n := len(b)
+	for col := 0; col < n; col++ {
+		pivot := col
+		for row := col + 1; row < n; row++ {
+			if math.Abs(a[row][col]) > math.Abs(a[pivot][col]) {
+				pivot = row
+			}
+		}
+		if a[pivot][col] == 0 {
+			return nil
+		}
+		a[col], a[pivot] = a[pivot], a[col]
+		b[col], b[pivot] = b[pivot], b[col]
+
+		for row := col + 1; row < n; row++ {
+			factor := a[row][col] / a[col][col]
+			for k := col; k < n; k++ {
+				a[row][k] -= factor * a[col][k]
+			}
+			b[row] -= factor * b[col]
+		}
+	}
+
+	x := make([]float64, n)
+	for row := n - 1; row >= 0; row-- {
+		sum := b[row]
+		for k := row + 1; k < n; k++ {
+			sum -= a[row][k] * x[k]
+		}
+		x[row] = sum / a[row][row]
+	}
+	return x
+}
+
+func randomFactors(rng *rand.Rand, n int) []float64 {
+	factors := make([]float64, n)
+	for i := range factors {
+		factors[i] = rng.NormFloat64() * 0.1
+	}
+	return factors
+}
@@ -0,0 +1,217 @@
+package ai
+
+import (
+	"math"
+	"sort"
+)
+
+// GroupStrategy is how the members' scores for a movie become the group's
+type GroupStrategy int
+
+const (
+	// Average ranks by the members' mean score
+	Average GroupStrategy = iota
+	// LeastMisery ranks by the lowest member's score, nobody has to sit through a
+	// movie they would hate
+	LeastMisery
+	// MostPleasure ranks by the highest member's score
+	MostPleasure
+	// Fairness builds the list one movie at a time, each time picking the movie that
+	// leaves the least satisfied member best off
+	Fairness
+)
+
+// GroupOptions picks the strategy and how many of each member's own top movies are
+// considered for the group
+type GroupOptions struct {
+	Strategy   GroupStrategy
+	Candidates int
+}
+
+// DefaultGroup is the average strategy over each member's top 100, a Candidates of 0
+// uses its 100
+var DefaultGroup = GroupOptions{Strategy: Average, Candidates: 100}
+
+// GroupRecommendation is a movie for the group, Score is the aggregated score and
+// Members each member's own
+type GroupRecommendation struct {
+	Rating
+	Members map[int]float64
+}
+
+// GroupResult is the group's list and how satisfied each member is with it, from 0 to
+// 1 as a share of what the member's own best list of the same length would give them
+type GroupResult struct {
+	Recommendations []GroupRecommendation
+	Satisfaction    map[int]float64
+}
+
+// RecommendGroup recommends n movies none of the members rated. Members are scored
+// with Predict when rec is a Predictor, Score when it is a Scorer and otherwise by
+// their own TopN list, where a movie missing from a member's list scores as the
+// bottom of it. Movies a Predictor or Scorer cannot score for every member are skipped.
+// Members are looked up in rec by ID, their Ratings only say which movies to leave out,
+// so a member rec was not trained on has to be added first, with AddRating when rec is
+// an Updater. The list is empty when n is not positive
+func RecommendGroup(rec Recommender, members Users, n int, opts GroupOptions) GroupResult {
+	if opts.Candidates <= 0 {
+		opts.Candidates = DefaultGroup.Candidates
+	}
+	seen := make(map[int]bool)
+	for _, member := range members {
+		for _, r := range member.Ratings {
+			seen[r.MovieID] = true
+		}
+	}
+
+	// lists are every member's own top movies, the candidates are all of them together
+	lists := make([]map[int]float64, len(members))
+	floors := make([]float64, len(members))
+	var candidates []int
+	added := make(map[int]bool)
+	for i, member := range members {
+		lists[i] = make(map[int]float64)
+		floors[i] = math.Inf(1)
+		for _, r := range rec.TopN(member.ID, opts.Candidates) {
+			lists[i][r.MovieID] = r.Score
+			floors[i] = math.Min(floors[i], r.Score)
+			if !seen[r.MovieID] && !added[r.MovieID] {
+				added[r.MovieID] = true
+				candidates = append(candidates, r.MovieID)
+			}
+		}
+	}
+	sort.Ints(candidates)
+
+	score := func(i, movieID int) (float64, bool) {
+		switch r := rec.(type) {
+		case Predictor:
+			return r.Predict(members[i].ID, movieID)
+		case Scorer:
+			return r.Score(members[i].ID, movieID)
+		}
+		if s, ok := lists[i][movieID]; ok {
+			return s, true
+		}
+		return floors[i], true
+	}
+
+	var recs []GroupRecommendation
+	for _, movieID := range candidates {
+		g := GroupRecommendation{Rating: Rating{MovieID: movieID}, Members: make(map[int]float64, len(members))}
+		scored := true
+		for i, member := range members {
+			s, ok := score(i, movieID)
+			if !ok {
+				scored = false
+				break
+			}
+			g.Members[member.ID] = s
+		}
+		if scored {
+			g.Score = aggregate(opts.Strategy, g.Members)
+			recs = append(recs, g)
+		}
+	}
+
+	normalised := normalise(members, recs)
+	ideal := idealScores(normalised, n)
+	if opts.Strategy == Fairness {
+		recs = fairest(members, recs, normalised, ideal, n)
+	} else {
+		sort.SliceStable(recs, func(i, j int) bool { return recs[i].Score > recs[j].Score })
+		recs = recs[:keep(n, len(recs))]
+	}
+
+	result := GroupResult{Recommendations: recs, Satisfaction: make(map[int]float64, len(members))}
+	for _, member := range members {
+		got := 0.0
+		for _, g := range recs {
+			got += normalised[member.ID][g.MovieID]
+		}
+		result.Satisfaction[member.ID] = ratio(got, ideal[member.ID])
+	}
+	return result
+}
+
+// aggregate combines the members' scores, Fairness ranks by the average until the list is built
+func aggregate(strategy GroupStrategy, scores map[int]float64) float64 {
+	result := 0.0
+	first := true
+	for _, s := range scores {
+		switch {
+		case strategy == LeastMisery && (first || s < result):
+			result = s
+		case strategy == MostPleasure && (first || s > result):
+			result = s
+		case strategy != LeastMisery && strategy != MostPleasure:
+			result += s / float64(len(scores))
+		}
+		first = false
+	}
+	return result
+}
+
+// normalise rescales every member's scores over the candidates to between 0 and 1, so
+// members are compared on how much they like a movie relative to the others rather
+// than on the scale of the recommender
+func normalise(members Users, recs []GroupRecommendation) map[int]map[int]float64 {
+	normalised := make(map[int]map[int]float64, len(members))
+	for _, member := range members {
+		low, high := math.Inf(1), math.Inf(-1)
+		for _, g := range recs {
+			low = math.Min(low, g.Members[member.ID])
+			high = math.Max(high, g.Members[member.ID])
+		}
+		normalised[member.ID] = make(map[int]float64, len(recs))
+		for _, g := range recs {
+			normalised[member.ID][g.MovieID] = ratio(g.Members[member.ID]-low, high-low)
+		}
+	}
+	return normalised
+}
+
+// idealScores is the most normalised score each member could get from any n candidates
+func idealScores(normalised map[int]map[int]float64, n int) map[int]float64 {
+	ideal := make(map[int]float64, len(normalised))
+	for memberID, scores := range normalised {
+		own := make([]float64, 0, len(scores))
+		for _, s := range scores {
+			own = append(own, s)
+		}
+		sort.Sort(sort.Reverse(sort.Float64Slice(own)))
+		for _, s := range own[:keep(n, len(own))] {
+			ideal[memberID] += s
+		}
+	}
+	return ideal
+}
+
+// fairest picks n movies greedily, each the one that leaves the least satisfied member
+// best off once added, ties going to the higher average
+func fairest(members Users, recs []GroupRecommendation, normalised map[int]map[int]float64, ideal map[int]float64, n int) []GroupRecommendation {
+	totals := make(map[int]float64, len(members))
+	used := make([]bool, len(recs))
+	var picked []GroupRecommendation
+	for len(picked) < n && len(picked) < len(recs) {
+		best, bestMin := -1, 0.0
+		for i, g := range recs {
+			if used[i] {
+				continue
+			}
+			least := math.Inf(1)
+			for _, member := range members {
+				least = math.Min(least, ratio(totals[member.ID]+normalised[member.ID][g.MovieID], ideal[member.ID]))
+			}
+			if best < 0 || least > bestMin || (least == bestMin && g.Score > recs[best].Score) {
+				best, bestMin = i, least
+			}
+		}
+		used[best] = true
+		picked = append(picked, recs[best])
+		for _, member := range members {
+			totals[member.ID] += normalised[member.ID][recs[best].MovieID]
+		}
+	}
+	return picked
+}
@@ -0,0 +1,208 @@
+package ai
+
+import "sync"
+
+// HybridStrategy is how a Hybrid combines its components
+type HybridStrategy int
+
+const (
+	// Weighted adds Bias to the weighted sum of every component's prediction
+	Weighted HybridStrategy = iota
+	// Switching answers with the first component that has enough support, so content
+	// takes over when too few neighbours rated the movie and too few ratings trained
+	// its factors
+	Switching
+	// Augmented is Weighted over collaborative components trained on ratings padded
+	// with content based pseudo ratings
+	Augmented
+)
+
+// HybridOptions picks the strategy and the settings of every component
+type HybridOptions struct {
+	Strategy      HybridStrategy
+	Metric        Similarity
+	UserBased     UserBasedOptions
+	Factorization FactorizationConfig
+	MinSupport    int // Switching skips a component with less support than this
+	Augment       int // pseudo ratings added per user by Augmented
+}
+
+var DefaultHybrid = HybridOptions{
+	Strategy:      Weighted,
+	Metric:        Pearson{},
+	UserBased:     DefaultUserBased,
+	Factorization: DefaultFactorization,
+	MinSupport:    3,
+	Augment:       20,
+}
+
+// Hybrid blends user based CF, matrix factorization and genre based content
+// filtering, in that order in Components
+type Hybrid struct {
+	Options    HybridOptions
+	Components []Predictor
+	Weights    []float64
+	Bias       float64
+	movieIDs   []int
+	matrix     *Matrix
+	mu         sync.RWMutex // written by AddRating and RemoveRating, read by everything else
+}
+
+// NewHybrid trains every component on users with equal blend weights, Fit learns
+// better ones
+func NewHybrid(users Users, movies Movies, opts HybridOptions) *Hybrid {
+	h := &Hybrid{Options: opts, matrix: NewMatrix(users)}
+	for _, m := range movies {
+		h.movieIDs = append(h.movieIDs, m.ID)
+	}
+
+	content := NewContentBased(users, movies)
+	training := users
+	if opts.Strategy == Augmented {
+		training = content.Augment(opts.Augment)
+	}
+	h.Components = []Predictor{
+		NewUserBased(training, opts.Metric, opts.UserBased),
+		NewFactorization(training, opts.Factorization),
+		content,
+	}
+	h.Weights = make([]float64, len(h.Components))
+	for i := range h.Weights {
+		h.Weights[i] = 1 / float64(len(h.Weights))
+	}
+	return h
+}
+
+// Predict combines the components with the hybrid's strategy
+func (h *Hybrid) Predict(userID, movieID int) (float64, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.predict(userID, movieID)
+}
+
+func (h *Hybrid) predict(userID, movieID int) (float64, bool) {
+	if h.Options.Strategy == Switching {
+		for _, c := range h.Components {
+			if s, ok := c.(Supporter); ok && s.Support(userID, movieID) < h.Options.MinSupport {
+				continue
+			}
+			if score, ok := c.Predict(userID, movieID); ok {
+				return score, true
+			}
+		}
+		return 0.0, false
+	}
+
+	x, ok := h.features(userID, movieID)
+	if !ok {
+		return 0.0, false
+	}
+	score := h.Bias
+	for i, w := range h.Weights {
+		score += w * x[i]
+	}
+	return score, true
+}
+
+// features is every component's prediction, the user's mean stands in for those that
+// cannot predict. ok is false when none of them can
+func (h *Hybrid) features(userID, movieID int) ([]float64, bool) {
+	x := make([]float64, len(h.Components))
+	predicted := false
+	for i, c := range h.Components {
+		score, ok := c.Predict(userID, movieID)
+		if !ok {
+			score = h.matrix.UserMean(userID)
+		}
+		x[i] = score
+		predicted = predicted || ok
+	}
+	return x, predicted
+}
+
+// TopN predicts every movie the user has not rated and returns the n best
+func (h *Hybrid) TopN(userID, n int) []Rating {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	row, ok := h.matrix.UserRow(userID)
+	if !ok {
+		return coldStart(h.matrix, userID, n)
+	}
+	var recs []Rating
+	for _, movieID := range h.movieIDs {
+		if _, ok := row.At(movieID); ok {
+			continue
+		}
+		if score, ok := h.predict(userID, movieID); ok {
+			recs = append(recs, Rating{MovieID: movieID, Score: score})
+		}
+	}
+	return topRatings(recs, n)
+}
+
+// Fit learns Bias and Weights by ridge regression of the validation ratings on the
+// component predictions. The validation users must be held out of training
+func (h *Hybrid) Fit(validation Users, regularization float64) {
+	size := len(h.Components) + 1
+	a := make([][]float64, size)
+	for i := range a {
+		a[i] = make([]float64, size)
+	}
+	b := make([]float64, size)
+
+	count := 0
+	x := make([]float64, size)
+	for _, user := range validation {
+		for _, r := range user.Ratings {
+			features, ok := h.features(user.ID, r.MovieID)
+			if !ok {
+				continue
+			}
+			x[0] = 1
+			copy(x[1:], features)
+			for i := range x {
+				b[i] += x[i] * r.Score
+				for j := range x {
+					a[i][j] += x[i] * x[j]
+				}
+			}
+			count++
+		}
+	}
+	// the bias is left unregularised so the weights only shrink towards zero
+	for i := 1; i < size; i++ {
+		a[i][i] += regularization * float64(count)
+	}
+
+	if w := solve(a, b); w != nil {
+		h.Bias = w[0]
+		copy(h.Weights, w[1:])
+	}
+}
+
+// AddRating passes the rating on to every component that takes updates
+func (h *Hybrid) AddRating(userID int, r Rating) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.matrix.AddRating(userID, r)
+	for _, c := range h.Components {
+		if u, ok := c.(Updater); ok {
+			u.AddRating(userID, r)
+		}
+	}
+}
+
+// RemoveRating is AddRating for a deleted rating
+func (h *Hybrid) RemoveRating(userID, movieID int) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !h.matrix.RemoveRating(userID, movieID) {
+		return false
+	}
+	for _, c := range h.Components {
+		if u, ok := c.(Updater); ok {
+			u.RemoveRating(userID, movieID)
+		}
+	}
+	return true
+}
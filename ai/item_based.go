@@ -0,0 +1,275 @@
+package ai
+
+import (
+	"context"
+	"math"
+	"sort"
+	"sync"
+)
+
+// Neighbour is another user or movie and how similar it is
+type Neighbour struct {
+	ID         int
+	Similarity float64
+}
+
+// ItemBased predicts scores from the user's own ratings of the most similar movies
+type ItemBased struct {
+	Neighbours map[int][]Neighbour
+	Metric     Similarity
+	Size       int
+	Time       TimeWeight // discounts the user's older ratings when predicting
+	MinSupport int        // neighbours the user must have rated for a prediction
+	Shrinkage  float64    // weight of the user's mean against the neighbours' ratings
+	matrix     *Matrix
+	mu         sync.RWMutex // written by AddRating and RemoveRating, read by everything else
+}
+
+// NewItemBased keeps the size most similar movies for every movie rated in users, all
+// of them when size is 0
+func NewItemBased(users Users, metric Similarity, size int) *ItemBased {
+	ib, _ := NewItemBasedContext(context.Background(), users, metric, size, PoolOptions{})
+	return ib
+}
+
+// NewItemBasedContext is NewItemBased with control over the worker pool, it stops
+// early when ctx is cancelled
+func NewItemBasedContext(ctx context.Context, users Users, metric Similarity, size int, opts PoolOptions) (*ItemBased, error) {
+	m := NewMatrix(users)
+	neighbours, err := m.MovieNeighbours(ctx, metric, size, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &ItemBased{Neighbours: neighbours, Metric: metric, Size: size, matrix: m}, nil
+}
+
+// Similar returns up to n movies most like movieID, best first
+func (ib *ItemBased) Similar(movieID, n int) []Neighbour {
+	ib.mu.RLock()
+	defer ib.mu.RUnlock()
+	list := ib.Neighbours[movieID]
+	return list[:keep(n, len(list))]
+}
+
+// Predict is the user's mean plus the similarity weighted average of how far the user
+// rated movieID's neighbours from it. It needs MinSupport rated neighbours, and Shrinkage
+// is added to the summed weights so a prediction resting on few neighbours stays near the mean
+func (ib *ItemBased) Predict(userID, movieID int) (float64, bool) {
+	ib.mu.RLock()
+	defer ib.mu.RUnlock()
+	return ib.predict(userID, movieID)
+}
+
+func (ib *ItemBased) predict(userID, movieID int) (float64, bool) {
+	mean := ib.matrix.UserMean(userID)
+	sum, weights := 0.0, 0.0
+	support := 0
+	for _, n := range ib.Neighbours[movieID] {
+		if score, at, ok := ib.matrix.ratingAt(userID, n.ID); ok {
+			w := ib.Time.Weight(at, ib.matrix.Latest())
+			sum += w * n.Similarity * (score - mean)
+			weights += w * math.Abs(n.Similarity)
+			support++
+		}
+	}
+	if weights == 0 || support < ib.MinSupport {
+		return 0.0, false
+	}
+	return mean + sum/(weights+ib.Shrinkage), true
+}
+
+// Support is the number of movieID's neighbours the user has rated
+func (ib *ItemBased) Support(userID, movieID int) int {
+	ib.mu.RLock()
+	defer ib.mu.RUnlock()
+	rated, _ := ib.matrix.UserRow(userID)
+	support := 0
+	for _, n := range ib.Neighbours[movieID] {
+		if _, ok := rated.At(n.ID); ok {
+			support++
+		}
+	}
+	return support
+}
+
+// TopN predicts every unrated neighbour of the user's movies and returns the n best
+func (ib *ItemBased) TopN(userID, n int) []Rating {
+	ib.mu.RLock()
+	defer ib.mu.RUnlock()
+	rated, ok := ib.matrix.UserRow(userID)
+	if !ok {
+		return coldStart(ib.matrix, userID, n)
+	}
+	candidates := make(map[int]bool)
+	for _, movieID := range rated.Index {
+		for _, neighbour := range ib.Neighbours[movieID] {
+			if _, ok := rated.At(neighbour.ID); !ok {
+				candidates[neighbour.ID] = true
+			}
+		}
+	}
+
+	recs := make([]Rating, 0, len(candidates))
+	for movieID := range candidates {
+		if score, ok := ib.predict(userID, movieID); ok {
+			recs = append(recs, Rating{MovieID: movieID, Score: score})
+		}
+	}
+	return topRatings(recs, n)
+}
+
+// AddRating updates the matrix and rescores the rated movie against every other movie
+func (ib *ItemBased) AddRating(userID int, r Rating) {
+	ib.mu.Lock()
+	defer ib.mu.Unlock()
+	ib.matrix.AddRating(userID, r)
+	ib.updated(r.MovieID)
+}
+
+// RemoveRating is AddRating for a deleted rating
+func (ib *ItemBased) RemoveRating(userID, movieID int) bool {
+	ib.mu.Lock()
+	defer ib.mu.Unlock()
+	if !ib.matrix.RemoveRating(userID, movieID) {
+		return false
+	}
+	ib.updated(movieID)
+	return true
+}
+
+// updated rebuilds the movie's own list and moves it within every other movie's list,
+// a list it falls out of the bottom of is rebuilt in full
+func (ib *ItemBased) updated(movieID int) {
+	col, rated := ib.matrix.MovieColumn(movieID)
+	var list []Neighbour
+	for _, otherID := range ib.matrix.MovieIDs() {
+		if otherID == movieID {
+			continue
+		}
+		n := Neighbour{ID: movieID}
+		if rated {
+			other, _ := ib.matrix.MovieColumn(otherID)
+			n.Similarity = ib.Metric.Similarity(other, col)
+		}
+		if n.Similarity > 0 {
+			list = append(list, Neighbour{ID: otherID, Similarity: n.Similarity})
+		}
+		updated, ok := updateNeighbours(ib.Neighbours[otherID], n, ib.Size)
+		if !ok {
+			updated = ib.similarTo(otherID)
+		}
+		ib.setNeighbours(otherID, updated)
+	}
+	ib.setNeighbours(movieID, bestNeighbours(list, ib.Size))
+}
+
+// similarTo scores movieID against every other movie
+func (ib *ItemBased) similarTo(movieID int) []Neighbour {
+	col, _ := ib.matrix.MovieColumn(movieID)
+	var list []Neighbour
+	for _, otherID := range ib.matrix.MovieIDs() {
+		if otherID == movieID {
+			continue
+		}
+		other, _ := ib.matrix.MovieColumn(otherID)
+		if sim := ib.Metric.Similarity(col, other); sim > 0 {
+			list = append(list, Neighbour{ID: otherID, Similarity: sim})
+		}
+	}
+	return bestNeighbours(list, ib.Size)
+}
+
+// setNeighbours stores list, movies without neighbours have no entry as in the table
+// the constructor builds
+func (ib *ItemBased) setNeighbours(movieID int, list []Neighbour) {
+	if len(list) == 0 {
+		delete(ib.Neighbours, movieID)
+		return
+	}
+	ib.Neighbours[movieID] = list
+}
+
+// updateNeighbours returns a copy of a list sorted by topNeighbours with n's entry
+// replaced, keeping at most limit entries when limit > 0. ok is false when n dropped
+// to the bottom of a full list, where an entry the list never held may now belong
+func updateNeighbours(list []Neighbour, n Neighbour, limit int) ([]Neighbour, bool) {
+	full := limit > 0 && len(list) >= limit
+	removed := false
+	at := -1
+	out := make([]Neighbour, 0, len(list)+1)
+	for _, other := range list {
+		if other.ID == n.ID {
+			removed = true
+			continue
+		}
+		if at < 0 && n.Similarity > 0 && ranksBefore(n, other) {
+			at = len(out)
+			out = append(out, n)
+		}
+		out = append(out, other)
+	}
+	if at < 0 && n.Similarity > 0 {
+		at = len(out)
+		out = append(out, n)
+	}
+	if limit > 0 && len(out) > limit {
+		if at >= limit {
+			at = -1
+		}
+		out = out[:limit]
+	}
+	if removed && full && (at < 0 || at == len(out)-1) {
+		return out, false
+	}
+	return out, true
+}
+
+func ranksBefore(a, b Neighbour) bool {
+	if a.Similarity != b.Similarity {
+		return a.Similarity > b.Similarity
+	}
+	return a.ID < b.ID
+}
+
+// topNeighbours sorts by similarity, ties broken by ID, and keeps the first n
+func topNeighbours(list []Neighbour, n int) []Neighbour {
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Similarity != list[j].Similarity {
+			return list[i].Similarity > list[j].Similarity
+		}
+		return list[i].ID < list[j].ID
+	})
+	n = keep(n, len(list))
+	return list[:n:n]
+}
+
+// bestNeighbours is topNeighbours keeping every neighbour when size is not positive,
+// the way updateNeighbours treats its limit
+func bestNeighbours(list []Neighbour, size int) []Neighbour {
+	if size <= 0 {
+		size = len(list)
+	}
+	return topNeighbours(list, size)
+}
+
+// topRatings sorts by score, ties broken by movie ID, and keeps the first n
+func topRatings(recs []Rating, n int) []Rating {
+	sort.Slice(recs, func(i, j int) bool {
+		if recs[i].Score != recs[j].Score {
+			return recs[i].Score > recs[j].Score
+		}
+		return recs[i].MovieID < recs[j].MovieID
+	})
+	return recs[:keep(n, len(recs))]
+}
+
+// keep is how many of length results a list cut to n holds, none when n is not positive
+func keep(n, length int) int {
+	if n <= 0 {
+		return 0
+	}
+	if n < length {
+		return n
+	}
+	return length
+}
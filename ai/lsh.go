@@ -0,0 +1,198 @@
+package ai
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// LSHFamily picks the hash functions of an LSH index and the measure candidates are
+// ranked by
+type LSHFamily int
+
+const (
+	// Hyperplane hashes on which side of random hyperplanes a vector falls, close in cosine
+	Hyperplane LSHFamily = iota
+	// PStable projects onto random Gaussian directions cut into buckets of Width, close
+	// in Euclidean distance
+	PStable
+)
+
+// LSHOptions trades accuracy for speed, more tables find more of the true neighbours
+// and more bits per table make each bucket smaller
+type LSHOptions struct {
+	Family LSHFamily
+	Tables int
+	Bits   int
+	Width  float64 // bucket width, PStable only
+	Seed   int64
+}
+
+var DefaultLSH = LSHOptions{Family: Hyperplane, Tables: 10, Bits: 8, Width: 4, Seed: 1}
+
+// LSH is an approximate nearest neighbour index over vectors such as item factors or
+// genre features. Only the vectors sharing a bucket with the query in some table are
+// scored
+type LSH struct {
+	Options LSHOptions
+	vectors map[int][]float64
+	ids     []int
+	planes  [][][]float64 // table, bit, dimension
+	offsets [][]float64   // table, bit
+	tables  []map[uint64][]int
+}
+
+// NewLSH hashes every vector into opts.Tables tables, all vectors need the same length
+func NewLSH(vectors map[int][]float64, opts LSHOptions) *LSH {
+	l := &LSH{
+		Options: opts,
+		vectors: vectors,
+		ids:     make([]int, 0, len(vectors)),
+		tables:  make([]map[uint64][]int, opts.Tables),
+	}
+	for id := range vectors {
+		l.ids = append(l.ids, id)
+	}
+	sort.Ints(l.ids)
+
+	dims := 0
+	if len(l.ids) > 0 {
+		dims = len(vectors[l.ids[0]])
+	}
+	rng := rand.New(rand.NewSource(opts.Seed))
+	l.planes = make([][][]float64, opts.Tables)
+	l.offsets = make([][]float64, opts.Tables)
+	for t := range l.tables {
+		l.planes[t] = make([][]float64, opts.Bits)
+		l.offsets[t] = make([]float64, opts.Bits)
+		for b := range l.planes[t] {
+			l.planes[t][b] = make([]float64, dims)
+			for d := range l.planes[t][b] {
+				l.planes[t][b][d] = rng.NormFloat64()
+			}
+			l.offsets[t][b] = rng.Float64() * opts.Width
+		}
+
+		l.tables[t] = make(map[uint64][]int)
+		for _, id := range l.ids {
+			key := l.key(t, vectors[id])
+			l.tables[t][key] = append(l.tables[t][key], id)
+		}
+	}
+	return l
+}
+
+// key hashes v for one table, every bit is a side of a hyperplane or a bucket number
+func (l *LSH) key(table int, v []float64) uint64 {
+	h := fnv.New64a()
+	var buf [8]byte
+	for b, plane := range l.planes[table] {
+		projection := dot(plane, v)
+		var bucket int64
+		switch l.Options.Family {
+		case PStable:
+			bucket = int64(math.Floor((projection + l.offsets[table][b]) / l.Options.Width))
+		default:
+			if projection >= 0 {
+				bucket = 1
+			}
+		}
+		binary.LittleEndian.PutUint64(buf[:], uint64(bucket))
+		h.Write(buf[:])
+	}
+	return h.Sum64()
+}
+
+// score is the cosine for Hyperplane and 1 / (1 + distance) for PStable, higher is
+// always closer
+func (l *LSH) score(a, b []float64) float64 {
+	if l.Options.Family == PStable {
+		sum := 0.0
+		for i := range a {
+			sum += (a[i] - b[i]) * (a[i] - b[i])
+		}
+		return 1 / (1 + math.Sqrt(sum))
+	}
+	return ratio(dot(a, b), norm(a, 0)*norm(b, 0))
+}
+
+// Candidates returns the IDs sharing a bucket with v in at least one table
+func (l *LSH) Candidates(v []float64) []int {
+	seen := make(map[int]bool)
+	var ids []int
+	for t := range l.tables {
+		for _, id := range l.tables[t][l.key(t, v)] {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids
+}
+
+// Query returns up to n of the candidates for v, closest first
+func (l *LSH) Query(v []float64, n int) []Neighbour {
+	return l.rank(v, l.Candidates(v), -1, n)
+}
+
+// Similar is Query for a vector already in the index, leaving the vector itself out
+func (l *LSH) Similar(id, n int) []Neighbour {
+	v, ok := l.vectors[id]
+	if !ok {
+		return nil
+	}
+	return l.rank(v, l.Candidates(v), id, n)
+}
+
+// Exact scores every vector in the index, it is what Similar approximates
+func (l *LSH) Exact(id, n int) []Neighbour {
+	v, ok := l.vectors[id]
+	if !ok {
+		return nil
+	}
+	return l.rank(v, l.ids, id, n)
+}
+
+func (l *LSH) rank(v []float64, ids []int, skip, n int) []Neighbour {
+	list := make([]Neighbour, 0, len(ids))
+	for _, id := range ids {
+		if id != skip {
+			list = append(list, Neighbour{ID: id, Similarity: l.score(v, l.vectors[id])})
+		}
+	}
+	return topNeighbours(list, n)
+}
+
+// Recall compares Similar with Exact for every id in ids. It returns the mean share
+// of the exact n nearest that were found, and the mean share of the index that had to
+// be scored per query
+func (l *LSH) Recall(ids []int, n int) (recall, scanned float64) {
+	queries := 0
+	for _, id := range ids {
+		v, ok := l.vectors[id]
+		if !ok || len(l.ids) < 2 {
+			continue
+		}
+		exact := l.Exact(id, n)
+		found := make(map[int]bool)
+		for _, nb := range l.Similar(id, n) {
+			found[nb.ID] = true
+		}
+		hits := 0
+		for _, nb := range exact {
+			if found[nb.ID] {
+				hits++
+			}
+		}
+		recall += ratio(float64(hits), float64(len(exact)))
+		scanned += float64(len(l.Candidates(v))-1) / float64(len(l.ids)-1)
+		queries++
+	}
+	if queries == 0 {
+		return 0.0, 0.0
+	}
+	return recall / float64(queries), scanned / float64(queries)
+}
@@ -0,0 +1,385 @@
+package ai
+
+import "sort"
+
+// Matrix is a sparse user by movie rating matrix. Ratings are stored twice, by user
+// (CSR) and by movie (CSC), so rows and columns can both be read as Vectors without
+// copying, and every row and column keeps its mean and norms
+type Matrix struct {
+	userIDs  []int
+	movieIDs []int
+	userRow  map[int]int
+	movieCol map[int]int
+
+	rowPtr   []int
+	rowMovie []int
+	rowScore []float64
+	rowTime  []int64
+
+	colPtr   []int
+	colUser  []int
+	colScore []float64
+
+	rowStats []stats
+	colStats []stats
+	mean     float64
+	latest   int64
+}
+
+// NewMatrix indexes the users' ratings, a movie rated twice by a user keeps the last score
+func NewMatrix(users Users) *Matrix {
+	m := &Matrix{userRow: make(map[int]int, len(users)), movieCol: make(map[int]int)}
+
+	latest := make(map[int]map[int]Rating, len(users))
+	for _, user := range users {
+		if _, ok := latest[user.ID]; !ok {
+			latest[user.ID] = make(map[int]Rating, len(user.Ratings))
+			m.userIDs = append(m.userIDs, user.ID)
+		}
+		for _, r := range user.Ratings {
+			latest[user.ID][r.MovieID] = r
+			if _, ok := m.movieCol[r.MovieID]; !ok {
+				m.movieCol[r.MovieID] = 0
+				m.movieIDs = append(m.movieIDs, r.MovieID)
+			}
+		}
+	}
+	sort.Ints(m.userIDs)
+	sort.Ints(m.movieIDs)
+	for row, id := range m.userIDs {
+		m.userRow[id] = row
+	}
+	for col, id := range m.movieIDs {
+		m.movieCol[id] = col
+	}
+
+	counts := make([]int, len(m.movieIDs)+1)
+	m.rowPtr = make([]int, 1, len(m.userIDs)+1)
+	for _, userID := range m.userIDs {
+		ratings := latest[userID]
+		start := len(m.rowMovie)
+		for movieID := range ratings {
+			m.rowMovie = append(m.rowMovie, movieID)
+			counts[m.movieCol[movieID]+1]++
+		}
+		sort.Ints(m.rowMovie[start:])
+		for _, movieID := range m.rowMovie[start:] {
+			m.rowScore = append(m.rowScore, ratings[movieID].Score)
+			m.rowTime = append(m.rowTime, ratings[movieID].Timestamp)
+		}
+		m.rowPtr = append(m.rowPtr, len(m.rowMovie))
+	}
+
+	// users are visited in ID order, so every column comes out sorted by user ID
+	m.colPtr = counts
+	for col := 1; col < len(m.colPtr); col++ {
+		m.colPtr[col] += m.colPtr[col-1]
+	}
+	next := make([]int, len(m.movieIDs))
+	copy(next, m.colPtr)
+	m.colUser = make([]int, len(m.rowMovie))
+	m.colScore = make([]float64, len(m.rowMovie))
+	for row, userID := range m.userIDs {
+		for i := m.rowPtr[row]; i < m.rowPtr[row+1]; i++ {
+			col := m.movieCol[m.rowMovie[i]]
+			m.colUser[next[col]] = userID
+			m.colScore[next[col]] = m.rowScore[i]
+			next[col]++
+		}
+	}
+
+	m.rowStats = make([]stats, len(m.userIDs))
+	for row := range m.userIDs {
+		m.rowStats[row] = newStats(m.rowScore[m.rowPtr[row]:m.rowPtr[row+1]])
+	}
+	m.colStats = make([]stats, len(m.movieIDs))
+	for col := range m.movieIDs {
+		m.colStats[col] = newStats(m.colScore[m.colPtr[col]:m.colPtr[col+1]])
+	}
+	m.mean = mean(m.rowScore)
+	for _, t := range m.rowTime {
+		if t > m.latest {
+			m.latest = t
+		}
+	}
+	return m
+}
+
+type entry struct {
+	userID    int
+	movieID   int
+	score     float64
+	timestamp int64
+}
+
+// UserIDs lists every user in ascending order
+func (m *Matrix) UserIDs() []int {
+	return m.userIDs
+}
+
+// MovieIDs lists every rated movie in ascending order
+func (m *Matrix) MovieIDs() []int {
+	return m.movieIDs
+}
+
+// Len is the number of ratings in the matrix
+func (m *Matrix) Len() int {
+	return len(m.rowScore)
+}
+
+// GlobalMean is the mean of every rating
+func (m *Matrix) GlobalMean() float64 {
+	return m.mean
+}
+
+// HasUser reports whether the user has any ratings
+func (m *Matrix) HasUser(userID int) bool {
+	_, ok := m.userRow[userID]
+	return ok
+}
+
+// HasMovie reports whether the movie has any ratings
+func (m *Matrix) HasMovie(movieID int) bool {
+	_, ok := m.movieCol[movieID]
+	return ok
+}
+
+// UserRow is the user's ratings indexed by movie ID
+func (m *Matrix) UserRow(userID int) (Vector, bool) {
+	row, ok := m.userRow[userID]
+	if !ok {
+		return Vector{}, false
+	}
+	start, end := m.rowPtr[row], m.rowPtr[row+1]
+	return Vector{Index: m.rowMovie[start:end:end], Value: m.rowScore[start:end:end], stats: &m.rowStats[row]}, true
+}
+
+// MovieColumn is the movie's ratings indexed by user ID
+func (m *Matrix) MovieColumn(movieID int) (Vector, bool) {
+	col, ok := m.movieCol[movieID]
+	if !ok {
+		return Vector{}, false
+	}
+	start, end := m.colPtr[col], m.colPtr[col+1]
+	return Vector{Index: m.colUser[start:end:end], Value: m.colScore[start:end:end], stats: &m.colStats[col]}, true
+}
+
+// UserTimes is when the user made each rating, in the order of UserRow
+func (m *Matrix) UserTimes(userID int) []int64 {
+	row, ok := m.userRow[userID]
+	if !ok {
+		return nil
+	}
+	start, end := m.rowPtr[row], m.rowPtr[row+1]
+	return m.rowTime[start:end:end]
+}
+
+// Latest is the Unix time of the newest rating ever added
+func (m *Matrix) Latest() int64 {
+	return m.latest
+}
+
+// Rating is the user's score for the movie
+func (m *Matrix) Rating(userID, movieID int) (float64, bool) {
+	row, ok := m.UserRow(userID)
+	if !ok {
+		return 0.0, false
+	}
+	return row.At(movieID)
+}
+
+// ratingAt is Rating with the time the rating was made
+func (m *Matrix) ratingAt(userID, movieID int) (float64, int64, bool) {
+	row, ok := m.userRow[userID]
+	if !ok {
+		return 0.0, 0, false
+	}
+	start, end := m.rowPtr[row], m.rowPtr[row+1]
+	i := start + sort.SearchInts(m.rowMovie[start:end], movieID)
+	if i == end || m.rowMovie[i] != movieID {
+		return 0.0, 0, false
+	}
+	return m.rowScore[i], m.rowTime[i], true
+}
+
+// UserMean is the user's average score, or the global mean for an unknown user
+func (m *Matrix) UserMean(userID int) float64 {
+	if row, ok := m.userRow[userID]; ok {
+		return m.rowStats[row].mean
+	}
+	return m.mean
+}
+
+// MovieMean is the movie's average score, or the global mean for an unknown movie
+func (m *Matrix) MovieMean(movieID int) float64 {
+	if col, ok := m.movieCol[movieID]; ok {
+		return m.colStats[col].mean
+	}
+	return m.mean
+}
+
+// Users turns the matrix back into users ordered by ID with ratings ordered by movie ID
+func (m *Matrix) Users() Users {
+	users := make(Users, len(m.userIDs))
+	for row, userID := range m.userIDs {
+		users[row].ID = userID
+		for i := m.rowPtr[row]; i < m.rowPtr[row+1]; i++ {
+			users[row].Ratings = append(users[row].Ratings, Rating{
+				MovieID:   m.rowMovie[i],
+				Score:     m.rowScore[i],
+				Timestamp: m.rowTime[i],
+			})
+		}
+	}
+	return users
+}
+
+// entries lists every rating in user then movie order
+func (m *Matrix) entries() []entry {
+	data := make([]entry, 0, m.Len())
+	for row, userID := range m.userIDs {
+		for i := m.rowPtr[row]; i < m.rowPtr[row+1]; i++ {
+			data = append(data, entry{userID: userID, movieID: m.rowMovie[i], score: m.rowScore[i], timestamp: m.rowTime[i]})
+		}
+	}
+	return data
+}
+
+// AddRating stores the user's rating, replacing any earlier score for the movie, and
+// refreshes the statistics of the one row and column it touches. Vectors read before
+// an update must not be used after it
+func (m *Matrix) AddRating(userID int, r Rating) {
+	row := m.addUser(userID)
+	col := m.addMovie(r.MovieID)
+
+	start, end := m.rowPtr[row], m.rowPtr[row+1]
+	i := start + sort.SearchInts(m.rowMovie[start:end], r.MovieID)
+	cs, ce := m.colPtr[col], m.colPtr[col+1]
+	j := cs + sort.SearchInts(m.colUser[cs:ce], userID)
+	if i < end && m.rowMovie[i] == r.MovieID {
+		m.mean += (r.Score - m.rowScore[i]) / float64(m.Len())
+		m.rowScore[i], m.rowTime[i] = r.Score, r.Timestamp
+		m.colScore[j] = r.Score
+	} else {
+		m.rowMovie = insertAt(m.rowMovie, i, r.MovieID)
+		m.rowScore = insertAt(m.rowScore, i, r.Score)
+		m.rowTime = insertAt(m.rowTime, i, r.Timestamp)
+		m.colUser = insertAt(m.colUser, j, userID)
+		m.colScore = insertAt(m.colScore, j, r.Score)
+		shift(m.rowPtr[row+1:], 1)
+		shift(m.colPtr[col+1:], 1)
+		m.mean += (r.Score - m.mean) / float64(m.Len())
+	}
+	if r.Timestamp > m.latest {
+		m.latest = r.Timestamp
+	}
+	m.refresh(row, col)
+}
+
+// RemoveRating deletes the user's rating of the movie, a user or movie left with no
+// ratings is dropped. It reports whether there was a rating to remove
+func (m *Matrix) RemoveRating(userID, movieID int) bool {
+	row, ok := m.userRow[userID]
+	if !ok {
+		return false
+	}
+	col, ok := m.movieCol[movieID]
+	if !ok {
+		return false
+	}
+	start, end := m.rowPtr[row], m.rowPtr[row+1]
+	i := start + sort.SearchInts(m.rowMovie[start:end], movieID)
+	if i == end || m.rowMovie[i] != movieID {
+		return false
+	}
+	cs, ce := m.colPtr[col], m.colPtr[col+1]
+	j := cs + sort.SearchInts(m.colUser[cs:ce], userID)
+
+	score := m.rowScore[i]
+	m.rowMovie = removeAt(m.rowMovie, i)
+	m.rowScore = removeAt(m.rowScore, i)
+	m.rowTime = removeAt(m.rowTime, i)
+	m.colUser = removeAt(m.colUser, j)
+	m.colScore = removeAt(m.colScore, j)
+	shift(m.rowPtr[row+1:], -1)
+	shift(m.colPtr[col+1:], -1)
+	if n := m.Len(); n > 0 {
+		m.mean = (m.mean*float64(n+1) - score) / float64(n)
+	} else {
+		m.mean = 0
+	}
+	m.refresh(row, col)
+
+	if m.rowPtr[row] == m.rowPtr[row+1] {
+		m.userIDs = removeAt(m.userIDs, row)
+		m.rowPtr = removeAt(m.rowPtr, row+1)
+		m.rowStats = removeAt(m.rowStats, row)
+		delete(m.userRow, userID)
+		reindex(m.userRow, m.userIDs, row)
+	}
+	if m.colPtr[col] == m.colPtr[col+1] {
+		m.movieIDs = removeAt(m.movieIDs, col)
+		m.colPtr = removeAt(m.colPtr, col+1)
+		m.colStats = removeAt(m.colStats, col)
+		delete(m.movieCol, movieID)
+		reindex(m.movieCol, m.movieIDs, col)
+	}
+	return true
+}
+
+// addUser returns the user's row, adding an empty one in ID order if needed
+func (m *Matrix) addUser(userID int) int {
+	if row, ok := m.userRow[userID]; ok {
+		return row
+	}
+	row := sort.SearchInts(m.userIDs, userID)
+	m.userIDs = insertAt(m.userIDs, row, userID)
+	m.rowPtr = insertAt(m.rowPtr, row+1, m.rowPtr[row])
+	m.rowStats = insertAt(m.rowStats, row, stats{})
+	reindex(m.userRow, m.userIDs, row)
+	return row
+}
+
+// addMovie returns the movie's column, adding an empty one in ID order if needed
+func (m *Matrix) addMovie(movieID int) int {
+	if col, ok := m.movieCol[movieID]; ok {
+		return col
+	}
+	col := sort.SearchInts(m.movieIDs, movieID)
+	m.movieIDs = insertAt(m.movieIDs, col, movieID)
+	m.colPtr = insertAt(m.colPtr, col+1, m.colPtr[col])
+	m.colStats = insertAt(m.colStats, col, stats{})
+	reindex(m.movieCol, m.movieIDs, col)
+	return col
+}
+
+func (m *Matrix) refresh(row, col int) {
+	m.rowStats[row] = newStats(m.rowScore[m.rowPtr[row]:m.rowPtr[row+1]])
+	m.colStats[col] = newStats(m.colScore[m.colPtr[col]:m.colPtr[col+1]])
+}
+
+// reindex points every ID from position from onwards at its position
+func reindex(positions map[int]int, ids []int, from int) {
+	for i := from; i < len(ids); i++ {
+		positions[ids[i]] = i
+	}
+}
+
+func shift(ptrs []int, by int) {
+	for i := range ptrs {
+		ptrs[i] += by
+	}
+}
+
+func insertAt[T any](s []T, i int, v T) []T {
+	var zero T
+	s = append(s, zero)
+	copy(s[i+1:], s[i:])
+	s[i] = v
+	return s
+}
+
+func removeAt[T any](s []T, i int) []T {
+	copy(s[i:], s[i+1:])
+	return s[:len(s)-1]
+}
@@ -0,0 +1,202 @@
+package ai
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// GenreCount is the number of genre flags on each line of u.item
+const GenreCount = 19
+
+// GenreNames lists the MovieLens 100k genres in the order of the u.item flags
+var GenreNames = [GenreCount]string{
+	"unknown", "Action", "Adventure", "Animation", "Children's", "Comedy", "Crime",
+	"Documentary", "Drama", "Fantasy", "Film-Noir", "Horror", "Musical", "Mystery",
+	"Romance", "Sci-Fi", "Thriller", "War", "Western",
+}
+
+const releaseDateLayout = "2-Jan-2006"
+
+// ParseError reports a malformed line in a MovieLens file
+type ParseError struct {
+	Path string
+	Line int
+	Err  error
+}
+
+func (e *ParseError) Error() string {
+	if e.Path == "" {
+		return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+	}
+	return fmt.Sprintf("%s:%d: %v", e.Path, e.Line, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// LoadMovieLens reads u.data and u.item from dir
+func LoadMovieLens(dir string) (Users, Movies, error) {
+	users, err := LoadRatings(filepath.Join(dir, "u.data"))
+	if err != nil {
+		return nil, nil, err
+	}
+	movies, err := LoadMovies(filepath.Join(dir, "u.item"))
+	if err != nil {
+		return nil, nil, err
+	}
+	return users, movies, nil
+}
+
+// LoadRatings reads a tab separated u.data file
+func LoadRatings(path string) (Users, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	users, err := ReadRatings(f)
+	return users, withPath(err, path)
+}
+
+// LoadMovies reads a pipe separated u.item file
+func LoadMovies(path string) (Movies, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	movies, err := ReadMovies(f)
+	return movies, withPath(err, path)
+}
+
+// ReadRatings parses "user item rating timestamp" lines into users ordered by ID
+func ReadRatings(r io.Reader) (Users, error) {
+	byID := make(map[int]*User)
+	err := scanLines(r, func(line string) error {
+		fields := strings.Split(line, "\t")
+		if len(fields) != 4 {
+			return fmt.Errorf("expected 4 tab separated fields, got %d", len(fields))
+		}
+		userID, err := strconv.Atoi(fields[0])
+		if err != nil {
+			return fmt.Errorf("bad user id: %w", err)
+		}
+		movieID, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return fmt.Errorf("bad item id: %w", err)
+		}
+		score, err := strconv.ParseFloat(fields[2], 64)
+		if err != nil {
+			return fmt.Errorf("bad rating: %w", err)
+		}
+		timestamp, err := strconv.ParseInt(fields[3], 10, 64)
+		if err != nil {
+			return fmt.Errorf("bad timestamp: %w", err)
+		}
+
+		user, ok := byID[userID]
+		if !ok {
+			user = &User{ID: userID}
+			byID[userID] = user
+		}
+		user.Ratings = append(user.Ratings, Rating{MovieID: movieID, Score: score, Timestamp: timestamp})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	users := make(Users, 0, len(byID))
+	for _, user := range byID {
+		users = append(users, *user)
+	}
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].ID < users[j].ID
+	})
+	return users, nil
+}
+
+// ReadMovies parses "id|title|release date|video release date|url|genres..." lines.
+// u.item is ISO-8859-1, lines that are not valid UTF-8 are decoded from it
+func ReadMovies(r io.Reader) (Movies, error) {
+	var movies Movies
+	err := scanLines(r, func(line string) error {
+		fields := strings.Split(latin1(line), "|")
+		if len(fields) != 5+GenreCount {
+			return fmt.Errorf("expected %d pipe separated fields, got %d", 5+GenreCount, len(fields))
+		}
+		id, err := strconv.Atoi(fields[0])
+		if err != nil {
+			return fmt.Errorf("bad movie id: %w", err)
+		}
+		movie := Movie{ID: id, Name: fields[1], URL: fields[4]}
+		if fields[2] != "" {
+			if movie.ReleaseDate, err = time.Parse(releaseDateLayout, fields[2]); err != nil {
+				return fmt.Errorf("bad release date: %w", err)
+			}
+		}
+		for i, flag := range fields[5:] {
+			switch flag {
+			case "0":
+			case "1":
+				movie.Genres[i] = true
+			default:
+				return fmt.Errorf("bad %s genre flag %q", GenreNames[i], flag)
+			}
+		}
+		movies = append(movies, movie)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return movies, nil
+}
+
+// scanLines calls parse for every non blank line, tagging failures with the line number
+func scanLines(r io.Reader, parse func(line string) error) error {
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if err := parse(line); err != nil {
+			return &ParseError{Line: lineNo, Err: err}
+		}
+	}
+	return scanner.Err()
+}
+
+// latin1 decodes an ISO-8859-1 line, every byte is the code point of the same value.
+// Valid UTF-8 is returned as is, for u.item that is every line with only ASCII
+func latin1(line string) string {
+	if utf8.ValidString(line) {
+		return line
+	}
+	runes := make([]rune, len(line))
+	for i := 0; i < len(line); i++ {
+		runes[i] = rune(line[i])
+	}
+	return string(runes)
+}
+
+func withPath(err error, path string) error {
+	if pe, ok := err.(*ParseError); ok {
+		pe.Path = path
+	}
+	return err
+}
@@ -0,0 +1,78 @@
+package ai
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestReadMoviesDecodesLatin1(t *testing.T) {
+	flags := strings.Repeat("|0", GenreCount)
+	tests := []struct {
+		name string
+		line string
+		want string
+	}{
+		{"latin1", "543|Mis\xe9rables, Les (1995)|01-Jan-1995||http://example.com" + flags, "Misérables, Les (1995)"},
+		{"ascii", "1|Toy Story (1995)|01-Jan-1995||http://example.com" + flags, "Toy Story (1995)"},
+		{"utf8", "2|Misérables, Les (1995)|01-Jan-1995||http://example.com" + flags, "Misérables, Les (1995)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			movies, err := ReadMovies(strings.NewReader(tt.line + "\n"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(movies) != 1 || movies[0].Name != tt.want {
+				t.Fatalf("got %v, want one movie named %q", movies, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadMoviesDecodesLatin1Titles(t *testing.T) {
+	movies, err := LoadMovies("u.item")
+	if err != nil {
+		t.Fatal(err)
+	}
+	titles := make(map[int]string, len(movies))
+	for _, m := range movies {
+		titles[m.ID] = m.Name
+	}
+	if got, want := titles[543], "Misérables, Les (1995)"; got != want {
+		t.Fatalf("movie 543 is %q, want %q", got, want)
+	}
+}
+
+func TestParseErrorsReportLineNumbers(t *testing.T) {
+	movie := "1|Toy Story (1995)|01-Jan-1995||http://example.com" + strings.Repeat("|0", GenreCount)
+	read := map[string]func(string) error{
+		"ratings": func(s string) error { _, err := ReadRatings(strings.NewReader(s)); return err },
+		"movies":  func(s string) error { _, err := ReadMovies(strings.NewReader(s)); return err },
+	}
+	tests := []struct {
+		name  string
+		read  string
+		input string
+		line  int
+	}{
+		{"first line", "ratings", "1\t2\tx\t3\n", 1},
+		{"after good lines", "ratings", "1\t2\t3\t4\n1\t3\t4\t5\n1\t4\n", 3},
+		{"blank lines counted", "ratings", "1\t2\t3\t4\n\n\n1\tx\t3\t4\n", 4},
+		{"crlf", "ratings", "1\t2\t3\t4\r\n1\t3\t4\r\n", 2},
+		{"bad genre flag", "movies", movie + "\n" + movie[:len(movie)-1] + "2\n", 2},
+		{"too few fields", "movies", "\n" + movie + "\n1|Toy Story (1995)\n", 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := read[tt.read](tt.input)
+			var pe *ParseError
+			if !errors.As(err, &pe) {
+				t.Fatalf("got error %v, want a *ParseError", err)
+			}
+			if pe.Line != tt.line {
+				t.Errorf("error on line %d, want line %d: %v", pe.Line, tt.line, err)
+			}
+		})
+	}
+}
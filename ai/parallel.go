@@ -0,0 +1,108 @@
+package ai
+
+import (
+	"context"
+	"runtime"
+	"sync"
+)
+
+// PoolOptions controls how similarity work is spread across goroutines
+type PoolOptions struct {
+	Workers  int                   // defaults to the number of CPUs
+	Progress func(done, total int) // called from one goroutine after every finished row
+}
+
+// UserNeighbours keeps the size most similar users for every user, all of them when
+// size is 0
+func (m *Matrix) UserNeighbours(ctx context.Context, metric Similarity, size int, opts PoolOptions) (map[int][]Neighbour, error) {
+	vectors := make([]Vector, len(m.userIDs))
+	for i, id := range m.userIDs {
+		vectors[i], _ = m.UserRow(id)
+	}
+	return neighbourTable(ctx, m.userIDs, vectors, metric, size, opts)
+}
+
+// MovieNeighbours keeps the size most similar movies for every movie, all of them when
+// size is 0
+func (m *Matrix) MovieNeighbours(ctx context.Context, metric Similarity, size int, opts PoolOptions) (map[int][]Neighbour, error) {
+	vectors := make([]Vector, len(m.movieIDs))
+	for i, id := range m.movieIDs {
+		vectors[i], _ = m.MovieColumn(id)
+	}
+	return neighbourTable(ctx, m.movieIDs, vectors, metric, size, opts)
+}
+
+// neighbourTable scores every pair of vectors once across a pool of workers. Each
+// worker owns whole rows and rows are merged in order afterwards, so the table is
+// the same whatever the number of workers
+func neighbourTable(ctx context.Context, ids []int, vectors []Vector, metric Similarity, size int, opts PoolOptions) (map[int][]Neighbour, error) {
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	type result struct {
+		row        int
+		neighbours []Neighbour
+	}
+	jobs := make(chan int)
+	results := make(chan result)
+
+	go func() {
+		defer close(jobs)
+		for i := range vectors {
+			select {
+			case jobs <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				var neighbours []Neighbour
+				for j := i + 1; j < len(vectors); j++ {
+					if sim := metric.Similarity(vectors[i], vectors[j]); sim > 0 {
+						neighbours = append(neighbours, Neighbour{ID: j, Similarity: sim})
+					}
+				}
+				results <- result{row: i, neighbours: neighbours}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	rows := make([][]Neighbour, len(vectors))
+	done := 0
+	for r := range results {
+		rows[r.row] = r.neighbours
+		done++
+		if opts.Progress != nil {
+			opts.Progress(done, len(vectors))
+		}
+	}
+	if done < len(vectors) {
+		return nil, ctx.Err()
+	}
+
+	table := make(map[int][]Neighbour, len(ids))
+	for i, row := range rows {
+		for _, n := range row {
+			a, b := ids[i], ids[n.ID]
+			table[a] = append(table[a], Neighbour{ID: b, Similarity: n.Similarity})
+			table[b] = append(table[b], Neighbour{ID: a, Similarity: n.Similarity})
+		}
+	}
+	for id, list := range table {
+		table[id] = bestNeighbours(list, size)
+	}
+	return table, nil
+}
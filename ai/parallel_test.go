@@ -0,0 +1,67 @@
+package ai
+
+import (
+	"context"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// randomUsers rates a random share of movies with whole scores so similarities tie
+func randomUsers(rng *rand.Rand, users, movies int) Users {
+	out := make(Users, users)
+	for u := range out {
+		out[u].ID = u + 1
+		for m := 1; m <= movies; m++ {
+			if rng.Float64() < 0.2 {
+				out[u].Ratings = append(out[u].Ratings, Rating{MovieID: m, Score: float64(1 + rng.Intn(5))})
+			}
+		}
+	}
+	return out
+}
+
+func TestNeighbourTableSameForAnyWorkers(t *testing.T) {
+	m := NewMatrix(randomUsers(rand.New(rand.NewSource(1)), 120, 80))
+	ctx := context.Background()
+	metrics := map[string]Similarity{"cosine": Cosine{}, "pearson": Pearson{}}
+
+	for name, metric := range metrics {
+		for _, size := range []int{0, 1, 10} {
+			users, err := m.UserNeighbours(ctx, metric, size, PoolOptions{Workers: 1})
+			if err != nil {
+				t.Fatal(err)
+			}
+			movies, err := m.MovieNeighbours(ctx, metric, size, PoolOptions{Workers: 1})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, workers := range []int{2, 3, 8} {
+				opts := PoolOptions{Workers: workers}
+				if got, _ := m.UserNeighbours(ctx, metric, size, opts); !reflect.DeepEqual(got, users) {
+					t.Errorf("%s size %d: user neighbours with %d workers differ from one worker", name, size, workers)
+				}
+				if got, _ := m.MovieNeighbours(ctx, metric, size, opts); !reflect.DeepEqual(got, movies) {
+					t.Errorf("%s size %d: movie neighbours with %d workers differ from one worker", name, size, workers)
+				}
+			}
+		}
+	}
+}
+
+func TestNeighbourTableReportsEveryRow(t *testing.T) {
+	m := NewMatrix(randomUsers(rand.New(rand.NewSource(2)), 50, 30))
+	done := 0
+	progress := func(d, total int) {
+		if d != done+1 || total != len(m.UserIDs()) {
+			t.Errorf("progress(%d, %d) after %d rows", d, total, done)
+		}
+		done = d
+	}
+	if _, err := m.UserNeighbours(context.Background(), Cosine{}, 5, PoolOptions{Workers: 4, Progress: progress}); err != nil {
+		t.Fatal(err)
+	}
+	if done != len(m.UserIDs()) {
+		t.Errorf("progress reached %d of %d rows", done, len(m.UserIDs()))
+	}
+}
@@ -0,0 +1,228 @@
+package ai
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// FormatVersion is bumped whenever the layout of a saved model changes
+const FormatVersion uint32 = 1
+
+var fileMagic = [4]byte{'G', 'L', 'R', 'M'}
+
+var (
+	ErrNotModelFile           = errors.New("not a saved model file")
+	ErrIncompatibleVersion    = errors.New("incompatible model file version")
+	ErrChecksumMismatch       = errors.New("model file checksum mismatch")
+	ErrUnsupportedRecommender = errors.New("recommender cannot be saved")
+)
+
+type fileHeader struct {
+	Magic    [4]byte
+	Version  uint32
+	Length   uint64
+	Checksum uint32
+}
+
+// modelFile is the gob encoded body of a saved model, the header in front of it is
+// magic, format version, body length and a CRC32 of the body
+type modelFile struct {
+	Kind          string
+	Config        Config
+	Created       time.Time
+	Users         Users
+	Neighbours    map[int][]Neighbour
+	Factorization *Factorization
+}
+
+// SaveModel writes rec, the config it was built from and its training ratings to path.
+// The model is written to a temporary file next to path and renamed over it, so a
+// crash part way leaves the previous model in place
+func SaveModel(path string, config Config, rec Recommender) error {
+	body, err := encodeModel(config, rec)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if err := writeModel(f, body); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return nil
+}
+
+// encodeModel gob encodes rec holding its read lock, so ratings added meanwhile wait
+// rather than changing the model half way through
+func encodeModel(config Config, rec Recommender) (*bytes.Buffer, error) {
+	file := modelFile{Config: config, Created: time.Now().UTC()}
+	switch r := rec.(type) {
+	case *UserBased:
+		r.mu.RLock()
+		defer r.mu.RUnlock()
+		file.Kind = AlgorithmUser
+		file.Users = r.Matrix.Users()
+	case *ItemBased:
+		r.mu.RLock()
+		defer r.mu.RUnlock()
+		file.Kind = AlgorithmItem
+		file.Users = r.matrix.Users()
+		file.Neighbours = r.Neighbours
+	case *Factorization:
+		r.mu.RLock()
+		defer r.mu.RUnlock()
+		file.Kind = AlgorithmFactorization
+		file.Users = r.matrix.Users()
+		file.Factorization = r
+	case *SlopeOne:
+		r.mu.RLock()
+		defer r.mu.RUnlock()
+		file.Kind = AlgorithmSlopeOne
+		file.Users = r.matrix.Users()
+	case *Markov:
+		file.Kind = AlgorithmMarkov
+		file.Users = r.matrix.Users()
+	default:
+		return nil, fmt.Errorf("%w: %T", ErrUnsupportedRecommender, rec)
+	}
+
+	var body bytes.Buffer
+	if err := gob.NewEncoder(&body).Encode(file); err != nil {
+		return nil, fmt.Errorf("could not encode model: %w", err)
+	}
+	return &body, nil
+}
+
+// writeModel writes the header and body to f and syncs it to disk
+func writeModel(f *os.File, body *bytes.Buffer) error {
+	if err := f.Chmod(0644); err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	header := fileHeader{fileMagic, FormatVersion, uint64(body.Len()), crc32.ChecksumIEEE(body.Bytes())}
+	if err := binary.Write(w, binary.BigEndian, header); err != nil {
+		return err
+	}
+	if _, err := body.WriteTo(w); err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return f.Sync()
+}
+
+// LoadModel reads a model written by SaveModel and returns it with its config
+func LoadModel(path string) (Recommender, Config, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, Config{}, err
+	}
+	defer f.Close()
+
+	rec, config, err := ReadModel(bufio.NewReader(f))
+	if err != nil {
+		return nil, Config{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return rec, config, nil
+}
+
+// LoadOrTrain loads the model at path, or builds config on users and saves it there
+// when there is no model yet or it was saved with another config. A file that is
+// there but cannot be read, such as one from an incompatible version, is an error
+// rather than being trained over
+func LoadOrTrain(path string, config Config, users Users) (Recommender, error) {
+	rec, saved, err := LoadModel(path)
+	switch {
+	case errors.Is(err, ErrIncompatibleVersion):
+		return nil, fmt.Errorf("%w, delete it to retrain", err)
+	case err == nil && saved == config:
+		return rec, nil
+	case err != nil && !errors.Is(err, os.ErrNotExist):
+		return nil, err
+	}
+
+	if rec, err = config.Build(users); err != nil {
+		return nil, err
+	}
+	if err := SaveModel(path, config, rec); err != nil {
+		return nil, fmt.Errorf("could not save model: %w", err)
+	}
+	return rec, nil
+}
+
+// ReadModel is LoadModel for an already open reader
+func ReadModel(r io.Reader) (Recommender, Config, error) {
+	var header fileHeader
+	if err := binary.Read(r, binary.BigEndian, &header); err != nil || header.Magic != fileMagic {
+		return nil, Config{}, ErrNotModelFile
+	}
+	if header.Version != FormatVersion {
+		return nil, Config{}, fmt.Errorf("%w: file is version %d, this build reads version %d",
+			ErrIncompatibleVersion, header.Version, FormatVersion)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r, int64(header.Length)))
+	if err != nil {
+		return nil, Config{}, err
+	}
+	if uint64(len(body)) != header.Length || crc32.ChecksumIEEE(body) != header.Checksum {
+		return nil, Config{}, ErrChecksumMismatch
+	}
+
+	var file modelFile
+	if err := gob.NewDecoder(bytes.NewReader(body)).Decode(&file); err != nil {
+		return nil, Config{}, fmt.Errorf("could not decode model: %w", err)
+	}
+
+	m := NewMatrix(file.Users)
+	switch file.Kind {
+	case AlgorithmFactorization:
+		if file.Factorization == nil {
+			break
+		}
+		file.Factorization.matrix = m
+		return file.Factorization, file.Config, nil
+	case AlgorithmItem:
+		metric, err := file.Config.similarity()
+		if err != nil {
+			return nil, Config{}, err
+		}
+		ib := &ItemBased{Neighbours: file.Neighbours, Metric: metric, Size: file.Config.Neighbours, Time: file.Config.timeWeight(), MinSupport: file.Config.MinSupport, Shrinkage: file.Config.Shrinkage, matrix: m}
+		return ib, file.Config, nil
+	case AlgorithmUser:
+		metric, err := file.Config.similarity()
+		if err != nil {
+			return nil, Config{}, err
+		}
+		ub := &UserBased{Matrix: m, Metric: metric, Options: file.Config.userBasedOptions(), Clusters: file.Config.clustering(file.Users)}
+		return ub, file.Config, nil
+	case AlgorithmSlopeOne:
+		// the deviation tables are cheap to recount from the ratings
+		return NewSlopeOne(file.Users, file.Config.BiPolar), file.Config, nil
+	case AlgorithmMarkov:
+		return NewMarkov(file.Users, file.Config.markovOptions()), file.Config, nil
+	}
+	return nil, Config{}, fmt.Errorf("%w: no %q model in file", ErrNotModelFile, file.Kind)
+}
@@ -0,0 +1,124 @@
+package ai
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSaveModelStoresEveryConfig(t *testing.T) {
+	users := randomUsers(rand.New(rand.NewSource(3)), 40, 30)
+	configs := []Config{
+		{Algorithm: AlgorithmUser, Metric: "pearson", Neighbours: 10},
+		{Algorithm: AlgorithmItem, Metric: "cosine", Neighbours: 10, MinSupport: 2, Shrinkage: 1},
+		{Algorithm: AlgorithmFactorization, Epochs: 2},
+		{Algorithm: AlgorithmSlopeOne},
+		{Algorithm: AlgorithmMarkov},
+	}
+	for _, config := range configs {
+		t.Run(config.Algorithm, func(t *testing.T) {
+			rec, err := config.Build(users)
+			if err != nil {
+				t.Fatal(err)
+			}
+			path := filepath.Join(t.TempDir(), "model.bin")
+			if err := SaveModel(path, config, rec); err != nil {
+				t.Fatal(err)
+			}
+			loaded, saved, err := LoadModel(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if saved != config {
+				t.Errorf("saved config %+v, want %+v", saved, config)
+			}
+			if got, want := loaded.TopN(1, 5), rec.TopN(1, 5); !reflect.DeepEqual(got, want) {
+				t.Errorf("loaded model recommends %v, the saved one %v", got, want)
+			}
+		})
+	}
+}
+
+func TestSaveModelLeavesNoTemporaryFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "model.bin")
+	rec := NewSlopeOne(randomUsers(rand.New(rand.NewSource(4)), 10, 10), false)
+	for i := 0; i < 2; i++ {
+		if err := SaveModel(path, Config{Algorithm: AlgorithmSlopeOne}, rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "model.bin" {
+		t.Fatalf("directory holds %v, want only model.bin", entries)
+	}
+}
+
+func TestReadModelRejectsDamagedFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "model.bin")
+	rec := NewSlopeOne(randomUsers(rand.New(rand.NewSource(5)), 10, 10), false)
+	if err := SaveModel(path, Config{Algorithm: AlgorithmSlopeOne}, rec); err != nil {
+		t.Fatal(err)
+	}
+	body, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the header is 4 bytes of magic, a 4 byte version, an 8 byte length and a 4 byte CRC
+	tests := []struct {
+		name   string
+		damage func(b []byte) []byte
+		want   error
+	}{
+		{"intact", func(b []byte) []byte { return b }, nil},
+		{"magic", func(b []byte) []byte { b[0] = 'X'; return b }, ErrNotModelFile},
+		{"empty", func(b []byte) []byte { return nil }, ErrNotModelFile},
+		{"newer version", func(b []byte) []byte { binary.BigEndian.PutUint32(b[4:], FormatVersion+1); return b }, ErrIncompatibleVersion},
+		{"older version", func(b []byte) []byte { binary.BigEndian.PutUint32(b[4:], 0); return b }, ErrIncompatibleVersion},
+		{"checksum", func(b []byte) []byte { b[16] ^= 0xff; return b }, ErrChecksumMismatch},
+		{"body", func(b []byte) []byte { b[len(b)-1] ^= 0xff; return b }, ErrChecksumMismatch},
+		{"truncated", func(b []byte) []byte { return b[:len(b)-10] }, ErrChecksumMismatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := tt.damage(append([]byte(nil), body...))
+			_, _, err := ReadModel(bytes.NewReader(b))
+			if !errors.Is(err, tt.want) {
+				t.Errorf("ReadModel = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadOrTrainKeepsIncompatibleFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "model.bin")
+	config := Config{Algorithm: AlgorithmSlopeOne}
+	users := randomUsers(rand.New(rand.NewSource(6)), 10, 10)
+	if err := SaveModel(path, config, NewSlopeOne(users, false)); err != nil {
+		t.Fatal(err)
+	}
+	body, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	binary.BigEndian.PutUint32(body[4:], FormatVersion+1)
+	if err := os.WriteFile(path, body, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := LoadOrTrain(path, config, users); !errors.Is(err, ErrIncompatibleVersion) {
+		t.Fatalf("LoadOrTrain = %v, want %v", err, ErrIncompatibleVersion)
+	}
+	if after, _ := os.ReadFile(path); !bytes.Equal(after, body) {
+		t.Error("LoadOrTrain overwrote the incompatible model")
+	}
+}
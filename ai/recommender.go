@@ -0,0 +1,30 @@
+package ai
+
+// Recommender returns the n movies it would suggest to a user, best first
+type Recommender interface {
+	TopN(userID, n int) []Rating
+}
+
+// Predictor estimates the score a user would give a movie, ok is false when it cannot
+type Predictor interface {
+	Predict(userID, movieID int) (float64, bool)
+}
+
+// Supporter reports how much evidence a prediction would rest on, such as the number
+// of neighbours that rated the movie
+type Supporter interface {
+	Support(userID, movieID int) int
+}
+
+// Scorer ranks movies for a user without claiming the score is a rating
+type Scorer interface {
+	Score(userID, movieID int) (float64, bool)
+}
+
+// Updater takes new ratings without retraining from scratch. Updates wait for the
+// reads in progress and block new ones, so a serving model can take them while it
+// answers requests. Exported fields such as Matrix are not guarded
+type Updater interface {
+	AddRating(userID int, r Rating)
+	RemoveRating(userID, movieID int) bool
+}
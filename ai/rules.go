@@ -0,0 +1,258 @@
+package ai
+
+import (
+	"math"
+	"math/bits"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// RuleOptions sets which movie sets are frequent and which rules between them are kept
+type RuleOptions struct {
+	MinSupport    float64 // share of users who rated every movie in a set
+	MinConfidence float64 // share of the users with the antecedent who also rated the consequent
+	MinLift       float64 // confidence over the share of all users who rated the consequent
+	MaxSize       int     // most movies in one set, antecedent and consequent together
+	MinScore      float64 // ratings below this leave a movie out of the user's set
+}
+
+var DefaultRules = RuleOptions{MinSupport: 0.1, MinConfidence: 0.5, MinLift: 1.5, MaxSize: 3}
+
+// ItemSet is a set of movies, in ascending ID order, rated together by Support of the users
+type ItemSet struct {
+	Movies  []int
+	Support float64
+}
+
+// Rule says users who rated every movie in Antecedent also rated Consequent
+type Rule struct {
+	Antecedent []int
+	Consequent int
+	Support    float64
+	Confidence float64
+	Lift       float64
+}
+
+// Rules are the association rules mined from the movies each user rated, strongest
+// first by lift then confidence
+type Rules struct {
+	Options  RuleOptions
+	ItemSets []ItemSet
+	Rules    []Rule
+	byMovie  map[int][]int // rules with just that movie as antecedent
+	matrix   *Matrix
+}
+
+// bitset has a bit for every user, set when the user rated all movies of an item set
+type bitset []uint64
+
+func (b bitset) and(other bitset) bitset {
+	out := make(bitset, len(b))
+	for i := range b {
+		out[i] = b[i] & other[i]
+	}
+	return out
+}
+
+func (b bitset) count() int {
+	n := 0
+	for _, w := range b {
+		n += bits.OnesCount64(w)
+	}
+	return n
+}
+
+// frequent is an item set along with the users behind it
+type frequent struct {
+	movies []int
+	users  bitset
+}
+
+// MineRules finds the frequent movie sets level by level with Apriori, a set of k
+// movies is only counted when two of its frequent k-1 subsets share all but their last
+// movie and every other k-1 subset is frequent too. The users of a set are the AND of
+// its parents' users, so nothing is rescanned
+func MineRules(users Users, opts RuleOptions) *Rules {
+	r := &Rules{Options: opts, byMovie: make(map[int][]int), matrix: NewMatrix(users)}
+	userIDs := r.matrix.UserIDs()
+	words := (len(userIDs) + 63) / 64
+	if len(userIDs) == 0 {
+		return r
+	}
+	minCount := int(math.Ceil(opts.MinSupport * float64(len(userIDs))))
+	if minCount < 1 {
+		minCount = 1
+	}
+
+	var level []frequent
+	for _, movieID := range r.matrix.MovieIDs() {
+		col, _ := r.matrix.MovieColumn(movieID)
+		users := make(bitset, words)
+		for i, userID := range col.Index {
+			if col.Value[i] >= opts.MinScore {
+				u := sort.SearchInts(userIDs, userID)
+				users[u/64] |= 1 << (u % 64)
+			}
+		}
+		if users.count() >= minCount {
+			level = append(level, frequent{movies: []int{movieID}, users: users})
+		}
+	}
+
+	support := make(map[string]float64)
+	for size := 1; len(level) > 0; size++ {
+		for _, f := range level {
+			s := float64(f.users.count()) / float64(len(userIDs))
+			support[setKey(f.movies)] = s
+			r.ItemSets = append(r.ItemSets, ItemSet{Movies: f.movies, Support: s})
+		}
+		if opts.MaxSize > 0 && size >= opts.MaxSize {
+			break
+		}
+		level = nextLevel(level, support, minCount)
+	}
+
+	for _, set := range r.ItemSets {
+		if len(set.Movies) < 2 {
+			continue
+		}
+		for i, consequent := range set.Movies {
+			antecedent := make([]int, 0, len(set.Movies)-1)
+			antecedent = append(antecedent, set.Movies[:i]...)
+			antecedent = append(antecedent, set.Movies[i+1:]...)
+			confidence := set.Support / support[setKey(antecedent)]
+			lift := confidence / support[setKey([]int{consequent})]
+			if confidence >= opts.MinConfidence && lift >= opts.MinLift {
+				r.Rules = append(r.Rules, Rule{
+					Antecedent: antecedent,
+					Consequent: consequent,
+					Support:    set.Support,
+					Confidence: confidence,
+					Lift:       lift,
+				})
+			}
+		}
+	}
+	sort.Slice(r.Rules, func(i, j int) bool { return strongerRule(r.Rules[i], r.Rules[j]) })
+	for i, rule := range r.Rules {
+		if len(rule.Antecedent) == 1 {
+			r.byMovie[rule.Antecedent[0]] = append(r.byMovie[rule.Antecedent[0]], i)
+		}
+	}
+	return r
+}
+
+// nextLevel joins the sets of one level that differ only in their last movie, level
+// is in order so those sets sit next to each other
+func nextLevel(level []frequent, support map[string]float64, minCount int) []frequent {
+	var next []frequent
+	for i := range level {
+		a := level[i].movies
+		for j := i + 1; j < len(level); j++ {
+			b := level[j].movies
+			if !samePrefix(a, b) {
+				break
+			}
+			movies := append(append([]int(nil), a...), b[len(b)-1])
+			if !subsetsFrequent(movies, support) {
+				continue
+			}
+			if users := level[i].users.and(level[j].users); users.count() >= minCount {
+				next = append(next, frequent{movies: movies, users: users})
+			}
+		}
+	}
+	return next
+}
+
+func samePrefix(a, b []int) bool {
+	for k := 0; k < len(a)-1; k++ {
+		if a[k] != b[k] {
+			return false
+		}
+	}
+	return true
+}
+
+// subsetsFrequent checks the subsets that leave out one of the shared movies, the two
+// that leave out the last or the one before it are the sets joined
+func subsetsFrequent(movies []int, support map[string]float64) bool {
+	subset := make([]int, 0, len(movies)-1)
+	for skip := 0; skip < len(movies)-2; skip++ {
+		subset = append(subset[:0], movies[:skip]...)
+		subset = append(subset, movies[skip+1:]...)
+		if _, ok := support[setKey(subset)]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func setKey(movies []int) string {
+	parts := make([]string, len(movies))
+	for i, id := range movies {
+		parts[i] = strconv.Itoa(id)
+	}
+	return strings.Join(parts, ",")
+}
+
+func strongerRule(a, b Rule) bool {
+	switch {
+	case a.Lift != b.Lift:
+		return a.Lift > b.Lift
+	case a.Confidence != b.Confidence:
+		return a.Confidence > b.Confidence
+	case a.Support != b.Support:
+		return a.Support > b.Support
+	case a.Consequent != b.Consequent:
+		return a.Consequent < b.Consequent
+	}
+	return setKey(a.Antecedent) < setKey(b.Antecedent)
+}
+
+// For returns up to n of the strongest rules from movieID alone to another movie,
+// people who rated movieID also rated the consequent. Like TopN it returns nothing
+// when n is not positive
+func (r *Rules) For(movieID, n int) []Rule {
+	ids := r.byMovie[movieID]
+	var rules []Rule
+	for _, i := range ids[:keep(n, len(ids))] {
+		rules = append(rules, r.Rules[i])
+	}
+	return rules
+}
+
+// TopN scores every movie the user has not rated by the highest confidence of a rule
+// leading to it from movies in the user's set
+func (r *Rules) TopN(userID, n int) []Rating {
+	row, ok := r.matrix.UserRow(userID)
+	if !ok {
+		return coldStart(r.matrix, userID, n)
+	}
+	scores := make(map[int]float64)
+	for _, rule := range r.Rules {
+		if _, rated := row.At(rule.Consequent); rated || rule.Confidence <= scores[rule.Consequent] {
+			continue
+		}
+		if r.holds(row, rule.Antecedent) {
+			scores[rule.Consequent] = rule.Confidence
+		}
+	}
+
+	recs := make([]Rating, 0, len(scores))
+	for movieID, score := range scores {
+		recs = append(recs, Rating{MovieID: movieID, Score: score})
+	}
+	return topRatings(recs, n)
+}
+
+// holds reports whether the user rated every movie of the antecedent at MinScore or more
+func (r *Rules) holds(row Vector, antecedent []int) bool {
+	for _, movieID := range antecedent {
+		if score, ok := row.At(movieID); !ok || score < r.Options.MinScore {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,138 @@
+package ai
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"sort"
+)
+
+// MarkovOptions sets how much of a user's history the next movie is predicted from
+type MarkovOptions struct {
+	History   int     // how many of the user's latest movies count
+	Decay     float64 // weight of each movie in the history relative to the one after it
+	Smoothing float64 // pseudo transitions spread over movies by how often they come next
+}
+
+var DefaultMarkov = MarkovOptions{History: 5, Decay: 0.6, Smoothing: 10}
+
+// Markov is a first order Markov chain over the order users rated movies in, it
+// predicts the next movie from the ones just before it. The chance of each next movie
+// is smoothed towards how often that movie follows anything, so rare predecessors
+// still rank every movie
+type Markov struct {
+	Options     MarkovOptions
+	Transitions map[int]map[int]float64 // times each movie was followed by another
+	Next        map[int]float64         // times each movie followed anything
+	total       float64
+	histories   map[int][]int
+	matrix      *Matrix
+}
+
+// Chronological is the user's ratings oldest first. u.data does not say which of the
+// ratings made in the same second came first, often a whole session, so they are put
+// in an order hashed from the user and movie rather than by movie ID, which would
+// teach the chain that low IDs come before high ones
+func Chronological(user User) []Rating {
+	sorted := append([]Rating(nil), user.Ratings...)
+	tie := func(r Rating) uint64 {
+		h := fnv.New64a()
+		binary.Write(h, binary.LittleEndian, [2]int64{int64(user.ID), int64(r.MovieID)})
+		return h.Sum64()
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Timestamp != sorted[j].Timestamp {
+			return sorted[i].Timestamp < sorted[j].Timestamp
+		}
+		return tie(sorted[i]) < tie(sorted[j])
+	})
+	return sorted
+}
+
+// NewMarkov counts every pair of movies a user rated one straight after the other
+func NewMarkov(users Users, opts MarkovOptions) *Markov {
+	mc := &Markov{
+		Options:     opts,
+		Transitions: make(map[int]map[int]float64),
+		Next:        make(map[int]float64),
+		histories:   make(map[int][]int, len(users)),
+		matrix:      NewMatrix(users),
+	}
+	for _, user := range users {
+		var history []int
+		for i, r := range Chronological(user) {
+			history = append(history, r.MovieID)
+			if i == 0 {
+				continue
+			}
+			from := history[i-1]
+			if mc.Transitions[from] == nil {
+				mc.Transitions[from] = make(map[int]float64)
+			}
+			mc.Transitions[from][r.MovieID]++
+			mc.Next[r.MovieID]++
+			mc.total++
+		}
+		mc.histories[user.ID] = append(mc.histories[user.ID], history...)
+	}
+	return mc
+}
+
+// PredictNext returns the n movies most likely to follow history, which runs oldest to
+// newest. Movies in the history are left out
+func (mc *Markov) PredictNext(history []int, n int) []Rating {
+	seen := make(map[int]bool, len(history))
+	for _, movieID := range history {
+		seen[movieID] = true
+	}
+	return mc.rank(history, seen, n)
+}
+
+// TopN predicts the user's next movie from their training history, leaving out
+// everything they already rated
+func (mc *Markov) TopN(userID, n int) []Rating {
+	history, ok := mc.histories[userID]
+	if !ok {
+		return coldStart(mc.matrix, userID, n)
+	}
+	seen := make(map[int]bool, len(history))
+	for _, movieID := range history {
+		seen[movieID] = true
+	}
+	return mc.rank(history, seen, n)
+}
+
+// rank scores every movie that ever came next by the decayed sum over the last
+// History movies of the smoothed chance it follows each of them
+func (mc *Markov) rank(history []int, seen map[int]bool, n int) []Rating {
+	if len(history) > mc.Options.History && mc.Options.History > 0 {
+		history = history[len(history)-mc.Options.History:]
+	}
+	type step struct {
+		from   map[int]float64
+		weight float64 // decay over the smoothed total, the same for every next movie
+	}
+	steps := make([]step, 0, len(history))
+	w := 1.0
+	for i := len(history) - 1; i >= 0; i-- {
+		total := 0.0
+		for _, count := range mc.Transitions[history[i]] {
+			total += count
+		}
+		steps = append(steps, step{from: mc.Transitions[history[i]], weight: ratio(w, total+mc.Options.Smoothing)})
+		w *= mc.Options.Decay
+	}
+
+	recs := make([]Rating, 0, len(mc.Next))
+	for movieID, count := range mc.Next {
+		if seen[movieID] {
+			continue
+		}
+		prior := mc.Options.Smoothing * count / mc.total
+		score := 0.0
+		for _, s := range steps {
+			score += s.weight * (s.from[movieID] + prior)
+		}
+		recs = append(recs, Rating{MovieID: movieID, Score: score})
+	}
+	return topRatings(recs, n)
+}
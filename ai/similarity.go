@@ -0,0 +1,230 @@
+package ai
+
+import (
+	"math"
+	"sort"
+)
+
+// Vector is a sparse rating vector, Index is kept in ascending order. Vectors read
+// from a Matrix share its storage and cached stats, so they must not be modified
+type Vector struct {
+	Index []int
+	Value []float64
+	stats *stats
+}
+
+type stats struct {
+	mean        float64
+	norm        float64
+	centredNorm float64
+}
+
+// Similarity scores how alike two rating vectors are
+type Similarity interface {
+	Similarity(a, b Vector) float64
+}
+
+// Cosine is the cosine of the angle between two vectors, unrated entries count as zero
+type Cosine struct{}
+
+// Pearson is the correlation of the co-rated entries around their co-rated means
+type Pearson struct{}
+
+// AdjustedCosine is the cosine of two vectors after each is centred on its own mean
+type AdjustedCosine struct{}
+
+// Jaccard is the overlap of the rated entries, ignoring the scores
+type Jaccard struct{}
+
+// Spearman is the Pearson correlation of the ranks of the co-rated entries
+type Spearman struct{}
+
+// Significance scales Metric down when two vectors share fewer than Threshold entries
+type Significance struct {
+	Metric    Similarity
+	Threshold int
+}
+
+func (Cosine) Similarity(a, b Vector) float64 {
+	return ratio(centredDot(a, b, 0, 0), a.summary().norm*b.summary().norm)
+}
+
+func (Pearson) Similarity(a, b Vector) float64 {
+	x, y := coRated(a, b)
+	return pearson(x, y)
+}
+
+func (AdjustedCosine) Similarity(a, b Vector) float64 {
+	sa, sb := a.summary(), b.summary()
+	return ratio(centredDot(a, b, sa.mean, sb.mean), sa.centredNorm*sb.centredNorm)
+}
+
+func (Jaccard) Similarity(a, b Vector) float64 {
+	shared := overlap(a, b)
+	union := len(a.Index) + len(b.Index) - shared
+	if union == 0 {
+		return 0.0
+	}
+	return float64(shared) / float64(union)
+}
+
+func (Spearman) Similarity(a, b Vector) float64 {
+	x, y := coRated(a, b)
+	return pearson(ranks(x), ranks(y))
+}
+
+func (s Significance) Similarity(a, b Vector) float64 {
+	sim := s.Metric.Similarity(a, b)
+	if n := overlap(a, b); n < s.Threshold {
+		sim *= float64(n) / float64(s.Threshold)
+	}
+	return sim
+}
+
+// At is the value stored at index
+func (v Vector) At(index int) (float64, bool) {
+	i := sort.SearchInts(v.Index, index)
+	if i < len(v.Index) && v.Index[i] == index {
+		return v.Value[i], true
+	}
+	return 0.0, false
+}
+
+func (v Vector) summary() stats {
+	if v.stats != nil {
+		return *v.stats
+	}
+	return newStats(v.Value)
+}
+
+func newStats(values []float64) stats {
+	m := mean(values)
+	return stats{mean: m, norm: norm(values, 0), centredNorm: norm(values, m)}
+}
+
+// centredDot is the dot product of the co-rated entries of a and b after subtracting
+// meanA and meanB
+func centredDot(a, b Vector, meanA, meanB float64) float64 {
+	sum := 0.0
+	for i, j := 0, 0; i < len(a.Index) && j < len(b.Index); {
+		switch {
+		case a.Index[i] < b.Index[j]:
+			i++
+		case a.Index[i] > b.Index[j]:
+			j++
+		default:
+			sum += (a.Value[i] - meanA) * (b.Value[j] - meanB)
+			i++
+			j++
+		}
+	}
+	return sum
+}
+
+// coRated returns the values of the entries present in both a and b
+func coRated(a, b Vector) ([]float64, []float64) {
+	var x, y []float64
+	for i, j := 0, 0; i < len(a.Index) && j < len(b.Index); {
+		switch {
+		case a.Index[i] < b.Index[j]:
+			i++
+		case a.Index[i] > b.Index[j]:
+			j++
+		default:
+			x = append(x, a.Value[i])
+			y = append(y, b.Value[j])
+			i++
+			j++
+		}
+	}
+	return x, y
+}
+
+func overlap(a, b Vector) int {
+	n := 0
+	for i, j := 0, 0; i < len(a.Index) && j < len(b.Index); {
+		switch {
+		case a.Index[i] < b.Index[j]:
+			i++
+		case a.Index[i] > b.Index[j]:
+			j++
+		default:
+			n++
+			i++
+			j++
+		}
+	}
+	return n
+}
+
+func pearson(x, y []float64) float64 {
+	if len(x) < 2 {
+		return 0.0
+	}
+	meanX, meanY := mean(x), mean(y)
+	num := 0.0
+	for i := range x {
+		num += (x[i] - meanX) * (y[i] - meanY)
+	}
+	return ratio(num, norm(x, meanX)*norm(y, meanY))
+}
+
+// ranks gives each value its 1-based rank, tied values share the average of their ranks
+func ranks(values []float64) []float64 {
+	order := make([]int, len(values))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool {
+		return values[order[i]] < values[order[j]]
+	})
+
+	r := make([]float64, len(values))
+	for i := 0; i < len(order); {
+		j := i
+		for j+1 < len(order) && values[order[j+1]] == values[order[i]] {
+			j++
+		}
+		rank := float64(i+j)/2 + 1
+		for k := i; k <= j; k++ {
+			r[order[k]] = rank
+		}
+		i = j + 1
+	}
+	return r
+}
+
+func dot(x, y []float64) float64 {
+	sum := 0.0
+	for i := range x {
+		sum += x[i] * y[i]
+	}
+	return sum
+}
+
+func mean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0.0
+	}
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+// norm is the length of values after subtracting centre from each of them
+func norm(values []float64, centre float64) float64 {
+	sum := 0.0
+	for _, v := range values {
+		sum += (v - centre) * (v - centre)
+	}
+	return math.Sqrt(sum)
+}
+
+func ratio(num, den float64) float64 {
+	if den == 0 {
+		return 0.0
+	}
+	return num / den
+}
@@ -0,0 +1,27 @@
+package ai
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCosineUsesBothMagnitudes(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b Vector
+		want float64
+	}{
+		{"parallel", Vector{Index: []int{1, 2}, Value: []float64{3, 4}}, Vector{Index: []int{1, 2}, Value: []float64{6, 8}}, 1},
+		{"scaled down", Vector{Index: []int{1, 2}, Value: []float64{6, 8}}, Vector{Index: []int{1, 2}, Value: []float64{0.3, 0.4}}, 1},
+		{"unrated counts as zero", Vector{Index: []int{1, 2}, Value: []float64{1, 1}}, Vector{Index: []int{1, 3}, Value: []float64{2, 2}}, 0.5},
+		{"disjoint", Vector{Index: []int{1}, Value: []float64{5}}, Vector{Index: []int{2}, Value: []float64{5}}, 0},
+		{"empty", Vector{}, Vector{Index: []int{1}, Value: []float64{5}}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := (Cosine{}).Similarity(tt.a, tt.b); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("Cosine.Similarity = %g, want %g", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,229 @@
+package ai
+
+import "sync"
+
+// deviation sums how much higher one movie was rated than another over the users who
+// rated both
+type deviation struct {
+	Sum   float64
+	Count int
+}
+
+// deviations is indexed by both movies of a pair, [i][j] holds the ratings of i less
+// those of j and [j][i] the same sum negated
+type deviations map[int]map[int]deviation
+
+// add counts one user's scores for i and j, sign -1 takes them out again. Pairs no
+// user rated any more are deleted
+func (d deviations) add(i, j int, si, sj float64, sign int) {
+	d.update(i, j, si-sj, sign)
+	d.update(j, i, sj-si, sign)
+}
+
+func (d deviations) update(i, j int, diff float64, sign int) {
+	row, ok := d[i]
+	if !ok {
+		row = make(map[int]deviation)
+		d[i] = row
+	}
+	dev := row[j]
+	dev.Sum += float64(sign) * diff
+	dev.Count += sign
+	if dev.Count > 0 {
+		row[j] = dev
+		return
+	}
+	delete(row, j)
+	if len(row) == 0 {
+		delete(d, i)
+	}
+}
+
+// SlopeOne is weighted Slope One, it predicts a movie from each movie the user rated
+// plus the average amount the two are rated apart, weighted by how many users rated
+// both. The bi-polar variant keeps separate tables for the movies users rated above
+// and below their mean, so a deviation only comes from users who liked both or
+// disliked both
+type SlopeOne struct {
+	BiPolar bool
+	tables  []deviations // one table, or liked then disliked for the bi-polar variant
+	matrix  *Matrix
+	mu      sync.RWMutex // written by AddRating and RemoveRating, read by everything else
+}
+
+// NewSlopeOne precomputes the deviation of every pair of movies rated by a user in common
+func NewSlopeOne(users Users, biPolar bool) *SlopeOne {
+	s := &SlopeOne{BiPolar: biPolar, matrix: NewMatrix(users)}
+	s.tables = []deviations{make(deviations)}
+	if biPolar {
+		s.tables = append(s.tables, make(deviations))
+	}
+	for _, userID := range s.matrix.UserIDs() {
+		s.account(userID, 1)
+	}
+	return s
+}
+
+// Deviation is the average amount i is rated above j and the number of users it
+// rests on, in the liked table for the bi-polar variant
+func (s *SlopeOne) Deviation(i, j int) (float64, int) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	dev := s.tables[0][i][j]
+	return ratio(dev.Sum, float64(dev.Count)), dev.Count
+}
+
+// polarity is the table a user's rating goes in, ok is false for a bi-polar rating
+// at exactly the user's mean
+func (s *SlopeOne) polarity(score, mean float64) (int, bool) {
+	switch {
+	case !s.BiPolar:
+		return 0, true
+	case score > mean:
+		return 0, true
+	case score < mean:
+		return 1, true
+	}
+	return 0, false
+}
+
+// account adds or with sign -1 removes every pair of the user's ratings
+func (s *SlopeOne) account(userID, sign int) {
+	row, _ := s.matrix.UserRow(userID)
+	mean := s.matrix.UserMean(userID)
+	for a, i := range row.Index {
+		pa, ok := s.polarity(row.Value[a], mean)
+		if !ok {
+			continue
+		}
+		for b := a + 1; b < len(row.Index); b++ {
+			if pb, ok := s.polarity(row.Value[b], mean); ok && pa == pb {
+				s.tables[pa].add(i, row.Index[b], row.Value[a], row.Value[b], sign)
+			}
+		}
+	}
+}
+
+// accountMovie is account for only the pairs that include movieID and its score
+func (s *SlopeOne) accountMovie(userID, movieID int, score float64, sign int) {
+	row, _ := s.matrix.UserRow(userID)
+	for b, j := range row.Index {
+		if j != movieID {
+			s.tables[0].add(movieID, j, score, row.Value[b], sign)
+		}
+	}
+}
+
+// Predict averages the user's ratings shifted by their deviations to movieID,
+// weighted by the number of users behind each deviation
+func (s *SlopeOne) Predict(userID, movieID int) (float64, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	row, ok := s.matrix.UserRow(userID)
+	if !ok {
+		return 0.0, false
+	}
+	mean := s.matrix.UserMean(userID)
+	sum, count := 0.0, 0
+	for a, i := range row.Index {
+		p, ok := s.polarity(row.Value[a], mean)
+		if !ok || i == movieID {
+			continue
+		}
+		if dev, ok := s.tables[p][movieID][i]; ok {
+			sum += dev.Sum + row.Value[a]*float64(dev.Count)
+			count += dev.Count
+		}
+	}
+	if count == 0 {
+		return 0.0, false
+	}
+	return sum / float64(count), true
+}
+
+// Support is the number of the user's rated movies with a deviation to movieID
+func (s *SlopeOne) Support(userID, movieID int) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	row, _ := s.matrix.UserRow(userID)
+	mean := s.matrix.UserMean(userID)
+	support := 0
+	for a, i := range row.Index {
+		if p, ok := s.polarity(row.Value[a], mean); ok {
+			if _, ok := s.tables[p][movieID][i]; ok && i != movieID {
+				support++
+			}
+		}
+	}
+	return support
+}
+
+// TopN walks the deviation table of every movie the user rated once, accumulating
+// all the unrated movies together rather than predicting them one at a time
+func (s *SlopeOne) TopN(userID, n int) []Rating {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	row, ok := s.matrix.UserRow(userID)
+	if !ok {
+		return coldStart(s.matrix, userID, n)
+	}
+	mean := s.matrix.UserMean(userID)
+	sums := make(map[int]float64)
+	counts := make(map[int]int)
+	for a, i := range row.Index {
+		p, ok := s.polarity(row.Value[a], mean)
+		if !ok {
+			continue
+		}
+		// [j][i] is the negated [i][j], so the deviation of j from i is -dev.Sum
+		for j, dev := range s.tables[p][i] {
+			if _, rated := row.At(j); !rated {
+				sums[j] += row.Value[a]*float64(dev.Count) - dev.Sum
+				counts[j] += dev.Count
+			}
+		}
+	}
+
+	recs := make([]Rating, 0, len(sums))
+	for movieID, sum := range sums {
+		recs = append(recs, Rating{MovieID: movieID, Score: sum / float64(counts[movieID])})
+	}
+	return topRatings(recs, n)
+}
+
+// AddRating updates the tables in place. Weighted Slope One only touches the pairs
+// with the rated movie, the bi-polar variant recounts all of the user's pairs since
+// the new rating moves the user's mean and with it which ratings count as liked
+func (s *SlopeOne) AddRating(userID int, r Rating) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.BiPolar {
+		s.account(userID, -1)
+		s.matrix.AddRating(userID, r)
+		s.account(userID, 1)
+		return
+	}
+	if old, ok := s.matrix.Rating(userID, r.MovieID); ok {
+		s.accountMovie(userID, r.MovieID, old, -1)
+	}
+	s.matrix.AddRating(userID, r)
+	s.accountMovie(userID, r.MovieID, r.Score, 1)
+}
+
+// RemoveRating is AddRating for a deleted rating
+func (s *SlopeOne) RemoveRating(userID, movieID int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	old, ok := s.matrix.Rating(userID, movieID)
+	if !ok {
+		return false
+	}
+	if s.BiPolar {
+		s.account(userID, -1)
+		s.matrix.RemoveRating(userID, movieID)
+		s.account(userID, 1)
+		return true
+	}
+	s.accountMovie(userID, movieID, old, -1)
+	return s.matrix.RemoveRating(userID, movieID)
+}
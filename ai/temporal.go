@@ -0,0 +1,99 @@
+package ai
+
+import (
+	"math"
+	"time"
+)
+
+// TimeWeight discounts old ratings. Ages are measured back from the newest rating in
+// the training data, the zero value weighs every rating the same
+type TimeWeight struct {
+	HalfLife time.Duration // a rating's weight halves every HalfLife, 0 disables decay
+	Window   time.Duration // ratings older than Window weigh nothing, 0 keeps them all
+}
+
+// Weight is the weight of a rating made at timestamp when the newest is at now, both
+// in Unix seconds
+func (w TimeWeight) Weight(timestamp, now int64) float64 {
+	age := time.Duration(now-timestamp) * time.Second
+	if age < 0 {
+		age = 0
+	}
+	if w.Window > 0 && age > w.Window {
+		return 0.0
+	}
+	if w.HalfLife > 0 {
+		return math.Exp2(-float64(age) / float64(w.HalfLife))
+	}
+	return 1.0
+}
+
+// Days is a duration of n days, for setting a TimeWeight from a config
+func Days(n float64) time.Duration {
+	return time.Duration(n * float64(24*time.Hour))
+}
+
+// binShrinkage is how many ratings with no residual every time bin starts with
+const binShrinkage = 25
+
+func (f *Factorization) initTime(data []entry) {
+	f.MinTime, f.MaxTime = data[0].timestamp, data[0].timestamp
+	for _, e := range data {
+		if e.timestamp < f.MinTime {
+			f.MinTime = e.timestamp
+		}
+		if e.timestamp > f.MaxTime {
+			f.MaxTime = e.timestamp
+		}
+	}
+	f.ItemBinBias = make(map[int][]float64)
+	for _, movieID := range f.matrix.MovieIDs() {
+		f.ItemBinBias[movieID] = make([]float64, f.Config.TimeBins)
+	}
+}
+
+// timeBias is the movie's bias in the bin of t, it is zero without TimeBins
+func (f *Factorization) timeBias(movieID int, t int64) float64 {
+	if bins, ok := f.ItemBinBias[movieID]; ok && f.Config.TimeBins > 0 {
+		return bins[f.bin(t)]
+	}
+	return 0.0
+}
+
+// fitBins sets every movie's bin biases to the mean residual of its ratings in each
+// bin, shrunk towards zero as if binShrinkage more ratings had no residual. Fitting
+// them by SGD along with the factors overfits, most movies have few ratings per bin
+func (f *Factorization) fitBins(data []entry) {
+	sums := make(map[int][]float64)
+	counts := make(map[int][]float64)
+	for _, e := range data {
+		if sums[e.movieID] == nil {
+			sums[e.movieID] = make([]float64, f.Config.TimeBins)
+			counts[e.movieID] = make([]float64, f.Config.TimeBins)
+		}
+		predicted, _ := f.predictAt(e.userID, e.movieID, e.timestamp)
+		b := f.bin(e.timestamp)
+		sums[e.movieID][b] += e.score - predicted
+		counts[e.movieID][b]++
+	}
+	for movieID, bins := range sums {
+		for b := range bins {
+			f.ItemBinBias[movieID][b] = bins[b] / (counts[movieID][b] + binShrinkage)
+		}
+	}
+}
+
+// bin is the time bin of t, times outside the training period go to the nearest bin
+func (f *Factorization) bin(t int64) int {
+	if f.MaxTime <= f.MinTime {
+		return 0
+	}
+	b := int(float64(t-f.MinTime) / float64(f.MaxTime-f.MinTime+1) * float64(f.Config.TimeBins))
+	if b < 0 {
+		return 0
+	}
+	if b >= f.Config.TimeBins {
+		return f.Config.TimeBins - 1
+	}
+	return b
+}
@@ -0,0 +1,85 @@
+package ai
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// updatable is a model whose predictions can be compared after updates
+type updatable interface {
+	Predictor
+	Updater
+}
+
+func TestUpdatesMatchRebuild(t *testing.T) {
+	rng := rand.New(rand.NewSource(7))
+	users := randomUsers(rng, 60, 40)
+
+	// train on users without every third rating and with some extra ones, then add
+	// the missing ratings and remove the extra ones
+	var start Users
+	var added, extra []userRating
+	for _, u := range users {
+		kept := User{ID: u.ID}
+		for i, r := range u.Ratings {
+			if i%3 == 0 {
+				added = append(added, userRating{u.ID, r})
+			} else {
+				kept.Ratings = append(kept.Ratings, r)
+			}
+		}
+		for m := 41; m <= 43; m++ {
+			if rng.Float64() < 0.3 {
+				r := Rating{MovieID: m, Score: float64(1 + rng.Intn(5))}
+				kept.Ratings = append(kept.Ratings, r)
+				extra = append(extra, userRating{u.ID, r})
+			}
+		}
+		start = append(start, kept)
+	}
+
+	models := map[string]func(Users) updatable{
+		"user": func(u Users) updatable {
+			return NewUserBased(u, Pearson{}, UserBasedOptions{})
+		},
+		"item": func(u Users) updatable {
+			return NewItemBased(u, Cosine{}, 0)
+		},
+		"slope one": func(u Users) updatable {
+			return NewSlopeOne(u, false)
+		},
+		"bi-polar slope one": func(u Users) updatable {
+			return NewSlopeOne(u, true)
+		},
+	}
+	for name, build := range models {
+		t.Run(name, func(t *testing.T) {
+			updated := build(start)
+			for _, a := range added {
+				updated.AddRating(a.userID, a.rating)
+			}
+			for _, e := range extra {
+				if !updated.RemoveRating(e.userID, e.rating.MovieID) {
+					t.Fatalf("RemoveRating(%d, %d) found no rating", e.userID, e.rating.MovieID)
+				}
+			}
+
+			rebuilt := build(users)
+			for _, u := range users {
+				for m := 1; m <= 43; m++ {
+					got, gotOK := updated.Predict(u.ID, m)
+					want, wantOK := rebuilt.Predict(u.ID, m)
+					if gotOK != wantOK || math.Abs(got-want) > 1e-9 {
+						t.Fatalf("Predict(%d, %d) = %g, %v after updates, %g, %v rebuilt", u.ID, m, got, gotOK, want, wantOK)
+					}
+				}
+			}
+		})
+	}
+}
+
+type userRating struct {
+	userID int
+	rating Rating
+}
@@ -0,0 +1,150 @@
+package ai
+
+import (
+	"math"
+	"sync"
+)
+
+// UserBased recommends with recommendFrom over a fixed set of users, each user's
+// neighbours are found once and reused by later calls
+type UserBased struct {
+	Matrix   *Matrix
+	Metric   Similarity
+	Options  UserBasedOptions
+	Clusters *Clustering // when set, neighbours only come from the user's own cluster
+
+	mu         sync.RWMutex // written by AddRating and RemoveRating, read by everything else
+	cacheMu    sync.Mutex
+	neighbours map[int][]Neighbour
+}
+
+func NewUserBased(users Users, metric Similarity, opts UserBasedOptions) *UserBased {
+	return &UserBased{Matrix: NewMatrix(users), Metric: metric, Options: opts}
+}
+
+// TopN falls back to the best movies by Bayesian average for an unknown user
+func (ub *UserBased) TopN(userID, n int) []Rating {
+	ub.mu.RLock()
+	defer ub.mu.RUnlock()
+	if !ub.Matrix.HasUser(userID) {
+		return coldStart(ub.Matrix, userID, n)
+	}
+	return topRatings(recommendFrom(ub.Matrix, userID, ub.neighboursOf(userID), ub.Options), n)
+}
+
+// Predict is the user's mean plus the weighted deviations of the neighbours who rated movieID
+func (ub *UserBased) Predict(userID, movieID int) (float64, bool) {
+	ub.mu.RLock()
+	defer ub.mu.RUnlock()
+	sum, weights := 0.0, 0.0
+	for _, n := range ub.neighboursOf(userID) {
+		if score, at, ok := ub.Matrix.ratingAt(n.ID, movieID); ok {
+			w := ub.Options.Time.Weight(at, ub.Matrix.Latest())
+			sum += w * n.Similarity * (score - ub.Matrix.UserMean(n.ID))
+			weights += w * math.Abs(n.Similarity)
+		}
+	}
+	if weights == 0 {
+		return 0.0, false
+	}
+	return ub.Matrix.UserMean(userID) + sum/weights, true
+}
+
+// Support is the number of the user's neighbours who rated movieID
+func (ub *UserBased) Support(userID, movieID int) int {
+	ub.mu.RLock()
+	defer ub.mu.RUnlock()
+	support := 0
+	for _, n := range ub.neighboursOf(userID) {
+		if _, ok := ub.Matrix.Rating(n.ID, movieID); ok {
+			support++
+		}
+	}
+	return support
+}
+
+// Neighbours is the user's nearest users, best first
+func (ub *UserBased) Neighbours(userID int) []Neighbour {
+	ub.mu.RLock()
+	defer ub.mu.RUnlock()
+	return ub.neighboursOf(userID)
+}
+
+// neighboursOf is Neighbours for callers already holding mu, readers share mu so the
+// cache has a lock of its own. Unknown users have no neighbours and are not cached, so
+// lookups of arbitrary IDs cannot grow the cache
+func (ub *UserBased) neighboursOf(userID int) []Neighbour {
+	if !ub.Matrix.HasUser(userID) {
+		return nil
+	}
+	ub.cacheMu.Lock()
+	defer ub.cacheMu.Unlock()
+	if list, ok := ub.neighbours[userID]; ok {
+		return list
+	}
+	if ub.neighbours == nil {
+		ub.neighbours = make(map[int][]Neighbour)
+	}
+	list := nearestUsers(ub.Matrix, userID, ub.candidates(userID), ub.Metric, ub.Options)
+	ub.neighbours[userID] = list
+	return list
+}
+
+// AddRating updates the matrix and rescores the user in every cached neighbour list
+func (ub *UserBased) AddRating(userID int, r Rating) {
+	ub.mu.Lock()
+	defer ub.mu.Unlock()
+	ub.Matrix.AddRating(userID, r)
+	ub.updated(userID)
+}
+
+// RemoveRating is AddRating for a deleted rating
+func (ub *UserBased) RemoveRating(userID, movieID int) bool {
+	ub.mu.Lock()
+	defer ub.mu.Unlock()
+	if !ub.Matrix.RemoveRating(userID, movieID) {
+		return false
+	}
+	ub.updated(userID)
+	return true
+}
+
+// updated forgets the user's own neighbours and rescores the user for everyone else,
+// a list the user falls out of the bottom of is dropped to be found again on demand
+func (ub *UserBased) updated(userID int) {
+	delete(ub.neighbours, userID)
+	user, _ := ub.Matrix.UserRow(userID)
+	for otherID, list := range ub.neighbours {
+		if !ub.peers(userID, otherID) {
+			continue
+		}
+		other, _ := ub.Matrix.UserRow(otherID)
+		n := Neighbour{ID: userID, Similarity: userSimilarity(other, user, ub.Metric, ub.Options)}
+		if list, ok := updateNeighbours(list, n, ub.Options.Neighbours); ok {
+			ub.neighbours[otherID] = list
+		} else {
+			delete(ub.neighbours, otherID)
+		}
+	}
+}
+
+// candidates is everyone the user may be compared with, their cluster when the model
+// is clustered and the user was clustered, otherwise every user
+func (ub *UserBased) candidates(userID int) []int {
+	if ub.Clusters != nil {
+		if peers, ok := ub.Clusters.Peers(userID); ok {
+			return peers
+		}
+	}
+	return ub.Matrix.UserIDs()
+}
+
+// peers reports whether userID is one of otherID's candidates
+func (ub *UserBased) peers(userID, otherID int) bool {
+	if ub.Clusters == nil {
+		return true
+	}
+	own, clustered := ub.Clusters.Assignments[userID]
+	other, ok := ub.Clusters.Assignments[otherID]
+	return !ok || (clustered && own == other)
+}
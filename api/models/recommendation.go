@@ -0,0 +1,92 @@
+package models
+
+import "github.com/graphql-go/graphql"
+
+type Recommendation struct {
+	MovieID    int            `json:"movieId"`
+	Title      string         `json:"title"`
+	Score      float64        `json:"score"`
+	Neighbours []Contribution `json:"neighbours,omitempty"`
+	Items      []Contribution `json:"items,omitempty"`
+	Genres     []string       `json:"genres,omitempty"`
+	Path       []Step         `json:"path,omitempty"`
+}
+
+// Contribution is a similar user, or a movie the user rated, behind a recommendation
+type Contribution struct {
+	ID           int     `json:"id"`
+	Title        string  `json:"title,omitempty"`
+	Similarity   float64 `json:"similarity"`
+	Score        float64 `json:"score"`
+	Contribution float64 `json:"contribution"`
+}
+
+// Step is a user or movie on the path from the user to the recommended movie
+type Step struct {
+	Kind  string `json:"kind"`
+	ID    int    `json:"id"`
+	Title string `json:"title,omitempty"`
+}
+
+var ContributionType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Contribution",
+	Fields: graphql.Fields{
+		"ID": &graphql.Field{
+			Type: graphql.Int,
+		},
+		"Title": &graphql.Field{
+			Type: graphql.String,
+		},
+		"Similarity": &graphql.Field{
+			Type: graphql.Float,
+		},
+		"Score": &graphql.Field{
+			Type: graphql.Float,
+		},
+		"Contribution": &graphql.Field{
+			Type: graphql.Float,
+		},
+	},
+})
+
+var StepType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Step",
+	Fields: graphql.Fields{
+		"Kind": &graphql.Field{
+			Type: graphql.String,
+		},
+		"ID": &graphql.Field{
+			Type: graphql.Int,
+		},
+		"Title": &graphql.Field{
+			Type: graphql.String,
+		},
+	},
+})
+
+var RecommendationType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Recommendation",
+	Fields: graphql.Fields{
+		"MovieID": &graphql.Field{
+			Type: graphql.Int,
+		},
+		"Title": &graphql.Field{
+			Type: graphql.String,
+		},
+		"Score": &graphql.Field{
+			Type: graphql.Float,
+		},
+		"Neighbours": &graphql.Field{
+			Type: graphql.NewList(ContributionType),
+		},
+		"Items": &graphql.Field{
+			Type: graphql.NewList(ContributionType),
+		},
+		"Genres": &graphql.Field{
+			Type: graphql.NewList(graphql.String),
+		},
+		"Path": &graphql.Field{
+			Type: graphql.NewList(StepType),
+		},
+	},
+})
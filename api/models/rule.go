@@ -0,0 +1,33 @@
+package models
+
+import "github.com/graphql-go/graphql"
+
+// AssociationRule is a movie people who rated the queried movie also rated
+type AssociationRule struct {
+	MovieID    int     `json:"movieId"`
+	Title      string  `json:"title"`
+	Support    float64 `json:"support"`
+	Confidence float64 `json:"confidence"`
+	Lift       float64 `json:"lift"`
+}
+
+var AssociationRuleType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "AssociationRule",
+	Fields: graphql.Fields{
+		"MovieID": &graphql.Field{
+			Type: graphql.Int,
+		},
+		"Title": &graphql.Field{
+			Type: graphql.String,
+		},
+		"Support": &graphql.Field{
+			Type: graphql.Float,
+		},
+		"Confidence": &graphql.Field{
+			Type: graphql.Float,
+		},
+		"Lift": &graphql.Field{
+			Type: graphql.Float,
+		},
+	},
+})
@@ -1,6 +1,8 @@
 package services
 
 import (
+	"log"
+
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/graphql-go/graphql"
@@ -14,6 +16,8 @@ var rootQuery = graphql.NewObject(graphql.ObjectConfig{
 		"movieByTitle":               getMovieByTitle,
 		"moviesWithinThreeRelations": moviesWithinThreeRelations,
 		"moviesByDirector":           moviesByDirector,
+		"recommendationsForUser":     recommendationsForUser,
+		"rulesForMovie":              rulesForMovie,
 	},
 })
 
@@ -22,6 +26,9 @@ var schema, _ = graphql.NewSchema(graphql.SchemaConfig{
 })
 
 func RunServer() {
+	if err := LoadModels(); err != nil {
+		log.Fatal(err)
+	}
 	router := gin.Default()
 	setupCors(router)
 
@@ -0,0 +1,136 @@
+package services
+
+import (
+	"fmt"
+	"github.com/graphql-go/graphql"
+	"golearn/ai"
+	models2 "golearn/api/models"
+	"sync"
+)
+
+// movieLensDir holds the u.data and u.item files recommendations are trained on
+const movieLensDir = "ai"
+
+// defaultLimit is how many results a query returns when it is not given a limit
+const defaultLimit = 10
+
+type recommendationModel struct {
+	recommender ai.Recommender
+	users       map[int]ai.User
+	movies      ai.Movies
+	titles      map[int]string
+}
+
+var (
+	loadModelOnce sync.Once
+	trainedModel  *recommendationModel
+	modelErr      error
+)
+
+// LoadModels loads the recommendation model up front, so a server fails at startup
+// rather than on its first request
+func LoadModels() error {
+	_, err := loadRecommendationModel()
+	return err
+}
+
+// loadRecommendationModel loads the model saved at ai.ModelPath the first time it is
+// needed, training the config at ai.ConfigPath and saving it when there is none yet
+func loadRecommendationModel() (*recommendationModel, error) {
+	loadModelOnce.Do(func() {
+		users, movies, err := ai.LoadMovieLens(movieLensDir)
+		if err != nil {
+			modelErr = fmt.Errorf("could not load ratings: %w", err)
+			return
+		}
+		config, err := ai.LoadServingConfig()
+		if err != nil {
+			modelErr = fmt.Errorf("could not load serving config: %w", err)
+			return
+		}
+		rec, err := ai.LoadOrTrain(ai.ModelPath(), config, users)
+		if err != nil {
+			modelErr = fmt.Errorf("could not load recommendation model: %w", err)
+			return
+		}
+		trainedModel = &recommendationModel{
+			recommender: rec,
+			users:       make(map[int]ai.User, len(users)),
+			movies:      movies,
+			titles:      make(map[int]string, len(movies)),
+		}
+		for _, user := range users {
+			trainedModel.users[user.ID] = user
+		}
+		for _, movie := range movies {
+			trainedModel.titles[movie.ID] = movie.Name
+		}
+	})
+	return trainedModel, modelErr
+}
+
+var recommendationsForUser = &graphql.Field{
+	Type: graphql.NewList(models2.RecommendationType),
+	Args: graphql.FieldConfigArgument{
+		"userId": &graphql.ArgumentConfig{
+			Type: graphql.NewNonNull(graphql.Int),
+		},
+		"limit": &graphql.ArgumentConfig{
+			Type:         graphql.Int,
+			DefaultValue: defaultLimit,
+		},
+	},
+	Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+		limit, ok := params.Args["limit"].(int)
+		if !ok {
+			limit = defaultLimit
+		}
+		return RecommendationsForUser(params.Args["userId"].(int), limit)
+	},
+}
+
+// RecommendationsForUser is the recommendationsForUser query of both GraphQL servers
+func RecommendationsForUser(userID, limit int) ([]models2.Recommendation, error) {
+	if limit <= 0 {
+		return nil, fmt.Errorf("limit must be positive, got %d", limit)
+	}
+
+	m, err := loadRecommendationModel()
+	if err != nil {
+		return nil, err
+	}
+	user, ok := m.users[userID]
+	if !ok {
+		user = ai.User{ID: userID}
+	}
+
+	var result []models2.Recommendation
+	for _, r := range ai.Recommend(m.recommender, user, m.movies, limit) {
+		result = append(result, m.toRecommendation(r))
+	}
+	return result, nil
+}
+
+func (m *recommendationModel) toRecommendation(r ai.Recommendation) models2.Recommendation {
+	e := r.Explanation
+	rec := models2.Recommendation{
+		MovieID: r.MovieID,
+		Title:   m.titles[r.MovieID],
+		Score:   r.Score,
+		Genres:  e.Genres,
+	}
+	for _, c := range e.Neighbours {
+		rec.Neighbours = append(rec.Neighbours, models2.Contribution{ID: c.ID, Similarity: c.Similarity, Score: c.Score, Contribution: c.Contribution})
+	}
+	for _, c := range e.Items {
+		rec.Items = append(rec.Items, models2.Contribution{ID: c.ID, Title: m.titles[c.ID], Similarity: c.Similarity, Score: c.Score, Contribution: c.Contribution})
+	}
+	for _, s := range e.Path {
+		step := models2.Step{Kind: s.Kind, ID: s.ID}
+		if s.Kind == "movie" {
+			step.Title = m.titles[s.ID]
+		}
+		rec.Path = append(rec.Path, step)
+	}
+	return rec
+}
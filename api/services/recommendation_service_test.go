@@ -0,0 +1,25 @@
+package services
+
+import "testing"
+
+func TestRecommendationsForUserRejectsLimits(t *testing.T) {
+	tests := []struct {
+		name  string
+		limit int
+	}{
+		{"zero", 0},
+		{"negative", -1},
+		{"most negative", -1 << 31},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recs, err := RecommendationsForUser(1, tt.limit)
+			if err == nil {
+				t.Fatalf("RecommendationsForUser(1, %d) = %v, want an error", tt.limit, recs)
+			}
+			if trainedModel != nil {
+				t.Fatal("the model was loaded for a limit that is rejected")
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"fmt"
+	"github.com/graphql-go/graphql"
+	"golearn/ai"
+	models2 "golearn/api/models"
+	"sync"
+)
+
+type ruleModel struct {
+	rules  *ai.Rules
+	titles map[int]string
+}
+
+var (
+	loadRulesOnce sync.Once
+	minedRules    *ruleModel
+	rulesErr      error
+)
+
+// loadRules mines the association rules the first time they are needed, only pairs
+// are mined since the shelf shows rules from a single movie
+func loadRules() (*ruleModel, error) {
+	loadRulesOnce.Do(func() {
+		users, movies, err := ai.LoadMovieLens(movieLensDir)
+		if err != nil {
+			rulesErr = fmt.Errorf("could not load ratings: %w", err)
+			return
+		}
+		opts := ai.DefaultRules
+		opts.MaxSize = 2
+		minedRules = &ruleModel{rules: ai.MineRules(users, opts), titles: make(map[int]string, len(movies))}
+		for _, movie := range movies {
+			minedRules.titles[movie.ID] = movie.Name
+		}
+	})
+	return minedRules, rulesErr
+}
+
+var rulesForMovie = &graphql.Field{
+	Type: graphql.NewList(models2.AssociationRuleType),
+	Args: graphql.FieldConfigArgument{
+		"movieId": &graphql.ArgumentConfig{
+			Type: graphql.NewNonNull(graphql.Int),
+		},
+		"limit": &graphql.ArgumentConfig{
+			Type:         graphql.Int,
+			DefaultValue: defaultLimit,
+		},
+		"minConfidence": &graphql.ArgumentConfig{
+			Type:         graphql.Float,
+			DefaultValue: ai.DefaultRules.MinConfidence,
+		},
+		"minLift": &graphql.ArgumentConfig{
+			Type:         graphql.Float,
+			DefaultValue: ai.DefaultRules.MinLift,
+		},
+	},
+	Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+		limit, ok := params.Args["limit"].(int)
+		if !ok {
+			limit = defaultLimit
+		}
+		minConfidence, ok := params.Args["minConfidence"].(float64)
+		if !ok {
+			minConfidence = ai.DefaultRules.MinConfidence
+		}
+		minLift, ok := params.Args["minLift"].(float64)
+		if !ok {
+			minLift = ai.DefaultRules.MinLift
+		}
+		return RulesForMovie(params.Args["movieId"].(int), limit, minConfidence, minLift)
+	},
+}
+
+// RulesForMovie is the rulesForMovie query of both GraphQL servers. The rules were
+// mined at the DefaultRules thresholds, minConfidence and minLift can only raise them
+func RulesForMovie(movieID, limit int, minConfidence, minLift float64) ([]models2.AssociationRule, error) {
+	if limit <= 0 {
+		return nil, fmt.Errorf("limit must be positive, got %d", limit)
+	}
+
+	m, err := loadRules()
+	if err != nil {
+		return nil, err
+	}
+	var result []models2.AssociationRule
+	for _, r := range m.rules.For(movieID, len(m.rules.Rules)) {
+		if len(result) == limit {
+			break
+		}
+		if r.Confidence < minConfidence || r.Lift < minLift {
+			continue
+		}
+		result = append(result, models2.AssociationRule{
+			MovieID:    r.Consequent,
+			Title:      m.titles[r.Consequent],
+			Support:    r.Support,
+			Confidence: r.Confidence,
+			Lift:       r.Lift,
+		})
+	}
+	return result, nil
+}
@@ -7,6 +7,9 @@ package graph
 import (
 	"context"
 	"fmt"
+	"golearn/ai"
+	"golearn/api/models"
+	"golearn/api/services"
 	"golearn/graph/model"
 )
 
@@ -44,6 +47,45 @@ func (r *queryResolver) Movies(ctx context.Context) ([]*model.Movie, error) {
 	return movies, nil
 }
 
+// RecommendationsForUser is the resolver for the recommendationsForUser field.
+func (r *queryResolver) RecommendationsForUser(ctx context.Context, userID int, limit *int) ([]*models.Recommendation, error) {
+	if limit == nil {
+		return nil, fmt.Errorf("limit must not be null")
+	}
+	recs, err := services.RecommendationsForUser(userID, *limit)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*models.Recommendation, len(recs))
+	for i := range recs {
+		result[i] = &recs[i]
+	}
+	return result, nil
+}
+
+// RulesForMovie is the resolver for the rulesForMovie field.
+func (r *queryResolver) RulesForMovie(ctx context.Context, movieID int, limit *int, minConfidence *float64, minLift *float64) ([]*models.AssociationRule, error) {
+	if limit == nil {
+		return nil, fmt.Errorf("limit must not be null")
+	}
+	confidence, lift := ai.DefaultRules.MinConfidence, ai.DefaultRules.MinLift
+	if minConfidence != nil {
+		confidence = *minConfidence
+	}
+	if minLift != nil {
+		lift = *minLift
+	}
+	rules, err := services.RulesForMovie(movieID, *limit, confidence, lift)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*models.AssociationRule, len(rules))
+	for i := range rules {
+		result[i] = &rules[i]
+	}
+	return result, nil
+}
+
 // Mutation returns MutationResolver implementation.
 func (r *Resolver) Mutation() MutationResolver { return &mutationResolver{r} }
 
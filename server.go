@@ -1,6 +1,7 @@
 package main
 
 import (
+	"golearn/api/services"
 	"golearn/graph"
 	"log"
 	"net/http"
@@ -18,6 +19,10 @@ func server() {
 		port = defaultPort
 	}
 
+	if err := services.LoadModels(); err != nil {
+		log.Fatal(err)
+	}
+
 	srv := handler.NewDefaultServer(graph.NewExecutableSchema(graph.Config{Resolvers: &graph.Resolver{}}))
 
 	http.Handle("/", playground.Handler("GraphQL playground", "/query"))